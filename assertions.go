@@ -0,0 +1,343 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	lipgloss "github.com/charmbracelet/lipgloss"
+)
+
+var testPassedStyle = responseBodyStyle.Foreground(lipgloss.Color("#0ead69"))
+var testFailedStyle = responseBodyStyle.Foreground(lipgloss.Color("#f25c54"))
+
+const assertionPlaceholder = "Enter assertion (ex. status == 200, json $.id exists, time < 500)"
+
+// assertion is one check on a response, written as a line like `status == 200` or `json $.user.name == "alex"`.
+// Assertions are stored as the text that was typed in and parsed when they're run, so one that doesn't parse is
+// reported as a failure instead of being lost.
+type assertion struct {
+	// subject is what's checked: status, header, json, body or time
+	subject string
+	// target is the header name or JSON path for header and json assertions
+	target   string
+	operator string
+	value    string
+}
+
+type assertionResult struct {
+	source string
+	passed bool
+	// detail says what was actually found when an assertion fails
+	detail string
+}
+
+var assertionOperators = map[string][]string{
+	"status": {"==", "!=", "<", "<=", ">", ">="},
+	"header": {"==", "!=", "~", "contains", "exists"},
+	"json":   {"==", "!=", "<", "<=", ">", ">=", "~", "contains", "exists"},
+	"body":   {"==", "~", "contains"},
+	"time":   {"<", "<=", ">", ">="},
+}
+
+// parseAssertion parses one assertion. Everything after the operator is the value, so values can have spaces in them
+// (ex. body contains hello world).
+func parseAssertion(source string) (assertion, error) {
+	subject, rest, _ := strings.Cut(strings.TrimSpace(source), " ")
+	operators, ok := assertionOperators[subject]
+	if !ok {
+		return assertion{}, fmt.Errorf("%q isn't something that can be checked, use status, header, json, body or time", subject)
+	}
+	a := assertion{subject: subject}
+	rest = strings.TrimSpace(rest)
+	if subject == "header" || subject == "json" {
+		a.target, rest, _ = strings.Cut(rest, " ")
+		if a.target == "" {
+			return assertion{}, fmt.Errorf("%s assertions need a %s to check", subject, map[string]string{"header": "header name", "json": "path"}[subject])
+		}
+		if subject == "json" {
+			if _, err := parseJSONPath(a.target); err != nil {
+				return assertion{}, err
+			}
+		}
+		rest = strings.TrimSpace(rest)
+	}
+	a.operator, a.value, _ = strings.Cut(rest, " ")
+	a.value = strings.TrimSpace(a.value)
+	if !slices.Contains(operators, a.operator) {
+		return assertion{}, fmt.Errorf("%s assertions can use %s, not %q", subject, strings.Join(operators, " "), a.operator)
+	}
+	if a.operator == "exists" {
+		if a.value != "" {
+			return assertion{}, fmt.Errorf("exists doesn't take a value")
+		}
+		return a, nil
+	}
+	if a.value == "" {
+		return assertion{}, fmt.Errorf("%s needs a value to compare with", a.operator)
+	}
+	if a.operator == "~" {
+		if _, err := regexp.Compile(a.value); err != nil {
+			return assertion{}, fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+	if subject == "status" && !statusPattern.MatchString(a.value) {
+		return assertion{}, fmt.Errorf("%q isn't a status code (ex. 200 or 2xx)", a.value)
+	}
+	if subject == "status" && strings.HasSuffix(a.value, "xx") && a.operator != "==" && a.operator != "!=" {
+		return assertion{}, fmt.Errorf("%s is a range of status codes, so it can only be compared with == or !=", a.value)
+	}
+	if subject == "time" {
+		if _, err := parseAssertionTime(a.value); err != nil {
+			return assertion{}, err
+		}
+	}
+	return a, nil
+}
+
+var statusPattern = regexp.MustCompile(`^[1-5]([0-9]{2}|xx)$`)
+
+// parseAssertionTime reads a plain number as milliseconds, since that's the unit response times are thought about in.
+func parseAssertionTime(s string) (time.Duration, error) {
+	if ms, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(ms * float64(time.Millisecond)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%q isn't a time (ex. 500 for milliseconds, or 1.5s)", s)
+	}
+	return d, nil
+}
+
+// evaluateAssertions runs every assertion in sources against response, in order.
+func evaluateAssertions(sources []string, response *ResponseData) []assertionResult {
+	results := []assertionResult{}
+	for _, source := range sources {
+		if strings.TrimSpace(source) == "" {
+			continue
+		}
+		a, err := parseAssertion(source)
+		if err != nil {
+			results = append(results, assertionResult{source: source, detail: err.Error()})
+			continue
+		}
+		passed, detail := a.evaluate(response)
+		results = append(results, assertionResult{source: source, passed: passed, detail: detail})
+	}
+	return results
+}
+
+func (a assertion) evaluate(response *ResponseData) (bool, string) {
+	switch a.subject {
+	case "status":
+		code, _, _ := strings.Cut(response.status, " ")
+		// 2xx matches any 2 hundred status, so it's only equal or not
+		if strings.HasSuffix(a.value, "xx") {
+			matches := strings.HasPrefix(code, a.value[:1])
+			return matches == (a.operator == "=="), "got " + response.status
+		}
+		actual, err := strconv.Atoi(code)
+		if err != nil {
+			return false, fmt.Sprintf("couldn't read status %q", response.status)
+		}
+		expected, _ := strconv.Atoi(a.value)
+		return compareOrdered(actual, expected, a.operator), "got " + response.status
+	case "header":
+		values, ok := response.header[http.CanonicalHeaderKey(a.target)]
+		if a.operator == "exists" {
+			return ok, "header isn't in the response"
+		}
+		if !ok {
+			return a.operator == "!=", "header isn't in the response"
+		}
+		actual := strings.Join(values, ", ")
+		return compareStrings(actual, a.value, a.operator), "got " + actual
+	case "json":
+		return a.evaluateJSON(response)
+	case "body":
+		body := string(response.rawBody)
+		return compareStrings(body, a.value, a.operator), fmt.Sprintf("body is %d bytes and doesn't match", len(body))
+	case "time":
+		limit, _ := parseAssertionTime(a.value)
+		actual := response.timing.total
+		return compareOrdered(actual, limit, a.operator), "took " + actual.Round(time.Millisecond).String()
+	}
+	return false, "unknown assertion"
+}
+
+func (a assertion) evaluateJSON(response *ResponseData) (bool, string) {
+	root := response.jsonTree
+	if root == nil {
+		var err error
+		if root, err = parseJSONTree(response.rawBody); err != nil {
+			return false, "body isn't JSON"
+		}
+	}
+	matches, _ := evaluateJSONPath(root, a.target)
+	if a.operator == "exists" {
+		return len(matches) > 0, "nothing at " + a.target
+	}
+	if len(matches) == 0 {
+		return a.operator == "!=", "nothing at " + a.target
+	}
+	// a path matching more than one value only passes when all of them do
+	for _, match := range matches {
+		if !a.compareJSON(match) {
+			return false, "got " + compactJSON(match)
+		}
+	}
+	return true, ""
+}
+
+func (a assertion) compareJSON(actual *jsonNode) bool {
+	switch a.operator {
+	case "==", "!=":
+		return jsonValuesEqual(actual, expectedJSON(a.value)) == (a.operator == "==")
+	case "~", "contains":
+		// strings are compared without their quotes, so json $.name ~ ^a works like it would on the value itself
+		text := compactJSON(actual)
+		if actual.kind == jsonString {
+			text = actual.value.(string)
+		}
+		return compareStrings(text, a.value, a.operator)
+	}
+	expected, err := strconv.ParseFloat(a.value, 64)
+	if actual.kind != jsonNumber || err != nil {
+		return false
+	}
+	number, err := actual.value.(json.Number).Float64()
+	return err == nil && compareOrdered(number, expected, a.operator)
+}
+
+// expectedJSON parses the value of a json assertion. Anything that isn't valid JSON is taken as a string, so
+// json $.name == alex works without quoting alex.
+func expectedJSON(value string) *jsonNode {
+	if node, err := parseJSONTree([]byte(value)); err == nil {
+		return node
+	}
+	return &jsonNode{kind: jsonString, value: value}
+}
+
+// jsonValuesEqual compares JSON values the way they'd be compared after decoding them, so key order and how numbers are
+// written (ex. 1.0 and 1) don't matter.
+func jsonValuesEqual(a, b *jsonNode) bool {
+	if a.kind != b.kind || len(a.children) != len(b.children) {
+		return false
+	}
+	switch a.kind {
+	case jsonObject:
+		for _, child := range a.children {
+			other := childWithKey(b, child.key)
+			if other == nil || !jsonValuesEqual(child, other) {
+				return false
+			}
+		}
+		return true
+	case jsonArray:
+		for i := range a.children {
+			if !jsonValuesEqual(a.children[i], b.children[i]) {
+				return false
+			}
+		}
+		return true
+	case jsonNumber:
+		x, errX := a.value.(json.Number).Float64()
+		y, errY := b.value.(json.Number).Float64()
+		if errX != nil || errY != nil {
+			return a.value == b.value
+		}
+		return x == y
+	}
+	return a.value == b.value
+}
+
+func compareStrings(actual, expected, operator string) bool {
+	switch operator {
+	case "==":
+		return actual == expected
+	case "!=":
+		return actual != expected
+	case "contains":
+		return strings.Contains(actual, expected)
+	case "~":
+		pattern, err := regexp.Compile(expected)
+		return err == nil && pattern.MatchString(actual)
+	}
+	return false
+}
+
+func compareOrdered[T int | float64 | time.Duration](actual, expected T, operator string) bool {
+	switch operator {
+	case "==":
+		return actual == expected
+	case "!=":
+		return actual != expected
+	case "<":
+		return actual < expected
+	case "<=":
+		return actual <= expected
+	case ">":
+		return actual > expected
+	case ">=":
+		return actual >= expected
+	}
+	return false
+}
+
+func countPassed(results []assertionResult) int {
+	passed := 0
+	for _, result := range results {
+		if result.passed {
+			passed++
+		}
+	}
+	return passed
+}
+
+func buildResponseTestsString(response *ResponseData) string {
+	if len(response.assertionResults) == 0 {
+		return "(no assertions for this query, add some in the Tests tab)\n"
+	}
+	testsString := fmt.Sprintf("%d/%d passed\n\n", countPassed(response.assertionResults), len(response.assertionResults))
+	for _, result := range response.assertionResults {
+		if result.passed {
+			testsString += testPassedStyle.Render("✓ "+result.source) + "\n"
+		} else {
+			testsString += testFailedStyle.Render(fmt.Sprintf("✗ %s (%s)", result.source, result.detail)) + "\n"
+		}
+	}
+	return testsString
+}
+
+func buildTestsTabString(m model) string {
+	testsTabString := ""
+	if len(m.currentQueryData.assertions) == 0 {
+		testsTabString += fmt.Sprintf("(no assertions, press %s to add one)\n", m.keys.ListAdd.Help().Key)
+	}
+	for i, source := range m.currentQueryData.assertions {
+		assertionString := " " + source
+		if i == m.focusedAssertion {
+			if m.uiState == UIStateEditingAssertion {
+				testsTabString += m.textInput.View() + "\n"
+			} else {
+				focusedStyle := tabOpenStyle
+				if m.uiState == UIStateSelectingQuery {
+					focusedStyle = responseBodyStyle
+				}
+				testsTabString += focusedStyle.Render(assertionString) + "\n"
+			}
+		} else {
+			testsTabString += assertionString + "\n"
+		}
+	}
+	if m.uiState == UIStateAddingAssertion {
+		testsTabString += m.textInput.View() + "\n"
+	}
+	return lipgloss.Place(m.mainTabWidth, m.bodyHeight, lipgloss.Left, lipgloss.Top, responseBodyStyle.Render(testsTabString),
+		lipgloss.WithWhitespaceBackground(responseBodyStyle.GetBackground())) + "\n"
+}
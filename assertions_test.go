@@ -0,0 +1,153 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseAssertion(t *testing.T) {
+	tests := []struct {
+		source  string
+		want    assertion
+		wantErr bool
+	}{
+		{source: "status == 200", want: assertion{subject: "status", operator: "==", value: "200"}},
+		{source: "  status   !=   2xx ", want: assertion{subject: "status", operator: "!=", value: "2xx"}},
+		{source: "status >= 400", want: assertion{subject: "status", operator: ">=", value: "400"}},
+		{source: "header Content-Type contains json", want: assertion{subject: "header", target: "Content-Type", operator: "contains", value: "json"}},
+		{source: "header X-Request-Id exists", want: assertion{subject: "header", target: "X-Request-Id", operator: "exists"}},
+		{source: `json $.user.name == "alex"`, want: assertion{subject: "json", target: "$.user.name", operator: "==", value: `"alex"`}},
+		{source: "json .items[].id > 3", want: assertion{subject: "json", target: ".items[].id", operator: ">", value: "3"}},
+		{source: "json $.name ~ ^al(ex)?$", want: assertion{subject: "json", target: "$.name", operator: "~", value: "^al(ex)?$"}},
+		{source: "body contains hello world", want: assertion{subject: "body", operator: "contains", value: "hello world"}},
+		{source: "time < 500", want: assertion{subject: "time", operator: "<", value: "500"}},
+		{source: "time <= 1.5s", want: assertion{subject: "time", operator: "<=", value: "1.5s"}},
+		{source: "", wantErr: true},
+		{source: "latency < 500", wantErr: true},
+		{source: "status", wantErr: true},
+		{source: "status == ", wantErr: true},
+		{source: "status ~ 200", wantErr: true},
+		{source: "status == 600", wantErr: true},
+		{source: "status == 2XX", wantErr: true},
+		{source: "status == ok", wantErr: true},
+		{source: "status < 3xx", wantErr: true},
+		{source: "status >= 2xx", wantErr: true},
+		{source: "header", wantErr: true},
+		{source: "header Content-Type exists yes", wantErr: true},
+		{source: "json", wantErr: true},
+		{source: "json items == 1", wantErr: true},
+		{source: "json $.id", wantErr: true},
+		{source: "body != hello", wantErr: true},
+		{source: "body ~ (", wantErr: true},
+		{source: "time == 500", wantErr: true},
+		{source: "time < soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			got, err := parseAssertion(tt.source)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseAssertion(%q) = %+v, want an error", tt.source, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAssertion(%q) returned error: %s", tt.source, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseAssertion(%q) = %+v, want %+v", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateAssertion(t *testing.T) {
+	response := &ResponseData{
+		status: "201 Created",
+		header: http.Header{"Content-Type": {"application/json; charset=utf-8"}, "Set-Cookie": {"a=1", "b=2"}},
+		rawBody: []byte(`{"id": 7, "price": 1.50, "name": "alex", "admin": false, "tags": ["x", "y"],
+			"owner": null, "items": [{"n": 1}, {"n": 2}]}`),
+		timing: responseTiming{total: 250 * time.Millisecond},
+	}
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{source: "status == 201", want: true},
+		{source: "status == 200", want: false},
+		{source: "status != 200", want: true},
+		{source: "status < 300", want: true},
+		{source: "status >= 300", want: false},
+		{source: "status == 2xx", want: true},
+		{source: "status != 2xx", want: false},
+		{source: "status == 4xx", want: false},
+		{source: "header content-type contains json", want: true},
+		{source: "header Content-Type == application/json", want: false},
+		{source: "header Content-Type ~ ^application/json", want: true},
+		{source: "header Set-Cookie == a=1, b=2", want: true},
+		{source: "header Content-Type exists", want: true},
+		{source: "header X-Missing exists", want: false},
+		{source: "header X-Missing == anything", want: false},
+		{source: "header X-Missing != anything", want: true},
+		{source: "json $.id == 7", want: true},
+		{source: "json $.id == 7.0", want: true},
+		{source: `json $.id == "7"`, want: false},
+		{source: "json $.price == 1.5", want: true},
+		{source: "json $.price > 1", want: true},
+		{source: "json $.price <= 1", want: false},
+		{source: "json $.name == alex", want: true},
+		{source: `json $.name == "alex"`, want: true},
+		{source: "json $.name != bob", want: true},
+		{source: "json $.name ~ ^al", want: true},
+		{source: "json $.name > 1", want: false},
+		{source: "json $.admin == false", want: true},
+		{source: "json $.owner == null", want: true},
+		{source: `json $.tags == ["x","y"]`, want: true},
+		{source: `json $.tags == ["y","x"]`, want: false},
+		{source: `json $.tags contains "y"`, want: true},
+		{source: "json $.items[*].n > 0", want: true},
+		{source: "json .items[].n > 1", want: false},
+		{source: "json $.items[-1].n == 2", want: true},
+		{source: "json $.missing exists", want: false},
+		{source: "json $.owner exists", want: true},
+		{source: "json $.missing == 1", want: false},
+		{source: "json $.missing != 1", want: true},
+		{source: "body contains alex", want: true},
+		{source: `body ~ "id":\s*7`, want: true},
+		{source: "body == alex", want: false},
+		{source: "time < 500", want: true},
+		{source: "time < 0.25s", want: false},
+		{source: "time <= 250ms", want: true},
+		{source: "time > 1m", want: false},
+		{source: "time >= 100us", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			a, err := parseAssertion(tt.source)
+			if err != nil {
+				t.Fatalf("parseAssertion(%q) returned error: %s", tt.source, err)
+			}
+			if got, detail := a.evaluate(response); got != tt.want {
+				t.Errorf("%q passed = %t, want %t (%s)", tt.source, got, tt.want, detail)
+			}
+		})
+	}
+}
+
+func TestEvaluateAssertionsReportsParseErrors(t *testing.T) {
+	response := &ResponseData{status: "200 OK", rawBody: []byte("not json")}
+	results := evaluateAssertions([]string{"status == 200", "", "json $.id exists", "nonsense"}, response)
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3 (blank lines are skipped)", len(results))
+	}
+	if !results[0].passed {
+		t.Errorf("%q failed: %s", results[0].source, results[0].detail)
+	}
+	if results[1].passed || results[1].detail != "body isn't JSON" {
+		t.Errorf("%q = %t (%s), want a failure because the body isn't JSON", results[1].source, results[1].passed, results[1].detail)
+	}
+	if results[2].passed || results[2].detail == "" {
+		t.Errorf("%q = %t (%s), want a failure saying why it didn't parse", results[2].source, results[2].passed, results[2].detail)
+	}
+}
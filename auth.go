@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	lipgloss "github.com/charmbracelet/lipgloss"
+)
+
+type authMode string
+
+const (
+	authNone   authMode = "none"
+	authBasic  authMode = "basic"
+	authBearer authMode = "bearer"
+	authAPIKey authMode = "apikey"
+	authOAuth2 authMode = "oauth2"
+)
+
+var authModes = []authMode{authNone, authBasic, authBearer, authAPIKey, authOAuth2}
+
+// cached OAuth2 tokens are fetched again this long before they expire, so one doesn't run out while a request is sent
+const oauthExpiryMargin = 30 * time.Second
+
+// queryAuth is how a query authenticates. It's turned into a header (or a query param for API keys) when the query's
+// sent, so secrets only have to be entered once instead of as a hand-built Authorization header. Only the fields for
+// mode are used, but the others are kept so switching modes back and forth doesn't lose them.
+type queryAuth struct {
+	mode     authMode
+	username string
+	password string
+	token    string
+	keyName  string
+	keyValue string
+	// keyInQuery sends the API key as a query param instead of a header
+	keyInQuery   bool
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scope        string
+}
+
+// authField is one editable row in the Auth tab, like settingField is for the Settings tab. Secret fields are masked
+// unless they're only variables.
+type authField struct {
+	label  string
+	secret bool
+	get    func(auth queryAuth) string
+	set    func(auth *queryAuth, value string) error
+}
+
+func stringAuthField(label string, secret bool, field func(auth *queryAuth) *string) authField {
+	return authField{
+		label:  label,
+		secret: secret,
+		get:    func(auth queryAuth) string { return *field(&auth) },
+		set: func(auth *queryAuth, value string) error {
+			*field(auth) = value
+			return nil
+		},
+	}
+}
+
+var authModeField = authField{
+	label: "mode",
+	get: func(auth queryAuth) string {
+		if auth.mode == "" {
+			return string(authNone)
+		}
+		return string(auth.mode)
+	},
+	set: func(auth *queryAuth, value string) error {
+		mode := authMode(strings.ToLower(value))
+		if value == "" {
+			mode = authNone
+		}
+		if !slices.Contains(authModes, mode) {
+			return fmt.Errorf("%q isn't an auth mode, use none, basic, bearer, apikey or oauth2", value)
+		}
+		auth.mode = mode
+		return nil
+	},
+}
+
+// authFields are the rows the Auth tab shows for mode, starting with the mode itself.
+func authFields(mode authMode) []authField {
+	switch mode {
+	case authBasic:
+		return []authField{
+			authModeField,
+			stringAuthField("username", false, func(auth *queryAuth) *string { return &auth.username }),
+			stringAuthField("password", true, func(auth *queryAuth) *string { return &auth.password }),
+		}
+	case authBearer:
+		return []authField{
+			authModeField,
+			stringAuthField("token", true, func(auth *queryAuth) *string { return &auth.token }),
+		}
+	case authAPIKey:
+		return []authField{
+			authModeField,
+			stringAuthField("key name", false, func(auth *queryAuth) *string { return &auth.keyName }),
+			stringAuthField("key value", true, func(auth *queryAuth) *string { return &auth.keyValue }),
+			{
+				label: "send key in",
+				get: func(auth queryAuth) string {
+					if auth.keyInQuery {
+						return "query"
+					}
+					return "header"
+				},
+				set: func(auth *queryAuth, value string) error {
+					switch strings.ToLower(value) {
+					case "header", "":
+						auth.keyInQuery = false
+					case "query":
+						auth.keyInQuery = true
+					default:
+						return fmt.Errorf("API keys can be sent in a header or the query, not %q", value)
+					}
+					return nil
+				},
+			},
+		}
+	case authOAuth2:
+		return []authField{
+			authModeField,
+			stringAuthField("token URL", false, func(auth *queryAuth) *string { return &auth.tokenURL }),
+			stringAuthField("client ID", false, func(auth *queryAuth) *string { return &auth.clientID }),
+			stringAuthField("client secret", true, func(auth *queryAuth) *string { return &auth.clientSecret }),
+			stringAuthField("scope", false, func(auth *queryAuth) *string { return &auth.scope }),
+		}
+	}
+	return []authField{authModeField}
+}
+
+// resolve returns auth with variables resolved in every field.
+func (auth queryAuth) resolve(vars map[string]string) queryAuth {
+	for _, field := range []*string{&auth.username, &auth.password, &auth.token, &auth.keyName, &auth.keyValue,
+		&auth.tokenURL, &auth.clientID, &auth.clientSecret, &auth.scope} {
+		*field = resolveVariables(*field, vars)
+	}
+	return auth
+}
+
+// authHeader is the header auth adds to requests, if it adds one. oauthToken is only used for OAuth2, which can't add
+// anything until a token's been fetched.
+func (auth queryAuth) authHeader(oauthToken string) (HeaderData, bool) {
+	switch auth.mode {
+	case authBasic:
+		credentials := base64.StdEncoding.EncodeToString([]byte(auth.username + ":" + auth.password))
+		return HeaderData{name: "Authorization", value: "Basic " + credentials}, true
+	case authBearer:
+		return HeaderData{name: "Authorization", value: "Bearer " + auth.token}, true
+	case authAPIKey:
+		if !auth.keyInQuery && auth.keyName != "" {
+			return HeaderData{name: auth.keyName, value: auth.keyValue}, true
+		}
+	case authOAuth2:
+		if oauthToken != "" {
+			return HeaderData{name: "Authorization", value: "Bearer " + oauthToken}, true
+		}
+	}
+	return HeaderData{}, false
+}
+
+// applyAuth adds query's auth to it. It replaces a header or param with the same name the query already has, since
+// the Auth tab is the more specific way of saying how to authenticate.
+func applyAuth(query QueryData, oauthToken string) QueryData {
+	if header, ok := query.auth.authHeader(oauthToken); ok {
+		query.headers = slices.DeleteFunc(slices.Clone(query.headers), func(existing HeaderData) bool {
+			return strings.EqualFold(existing.name, header.name)
+		})
+		query.headers = append(query.headers, header)
+	}
+	if query.auth.mode == authAPIKey && query.auth.keyInQuery && query.auth.keyName != "" {
+		query.queryParams = slices.DeleteFunc(slices.Clone(query.queryParams), func(existing QueryParamData) bool {
+			return existing.name == query.auth.keyName
+		})
+		query.queryParams = append(query.queryParams, QueryParamData{name: query.auth.keyName, value: query.auth.keyValue})
+	}
+	return query
+}
+
+const maskedSecret = "********"
+
+// maskSecret hides value, unless it's nothing but variables (ex. {{apiToken}}), since those don't give anything away.
+func maskSecret(value string) string {
+	if strings.TrimSpace(variablePattern.ReplaceAllString(value, "")) == "" {
+		return value
+	}
+	return maskedSecret
+}
+
+// maskAuthorization hides the credentials in an Authorization header's value but keeps its scheme, so it's still
+// clear whether it's Basic, Bearer or something else.
+func maskAuthorization(value string) string {
+	scheme, credentials, ok := strings.Cut(strings.TrimSpace(value), " ")
+	if !ok {
+		return maskSecret(value)
+	}
+	return scheme + " " + maskSecret(credentials)
+}
+
+// oauthToken is an access token from an OAuth2 token endpoint. Tokens without an expiry are kept until the program's
+// closed or they're cleared in the Auth tab.
+type oauthToken struct {
+	accessToken string
+	expires     time.Time
+}
+
+func (token oauthToken) valid() bool {
+	return token.accessToken != "" && (token.expires.IsZero() || time.Now().Add(oauthExpiryMargin).Before(token.expires))
+}
+
+// oauthCacheKey is what tokens are cached by, so queries using the same client share a token and changing any of its
+// settings fetches a new one.
+func (auth queryAuth) oauthCacheKey() string {
+	return strings.Join([]string{auth.tokenURL, auth.clientID, auth.clientSecret, auth.scope}, "\x00")
+}
+
+// fetchOAuthToken gets a token with the client credentials grant. The client's credentials are sent with Basic auth
+// like RFC 6749 asks for, and again in the form if that's rejected, since some servers only take them there.
+func fetchOAuthToken(ctx context.Context, auth queryAuth, timeout time.Duration) (oauthToken, error) {
+	if auth.tokenURL == "" {
+		return oauthToken{}, fmt.Errorf("the Auth tab needs a token URL")
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	token, status, err := requestOAuthToken(ctx, auth, false)
+	if status == http.StatusBadRequest || status == http.StatusUnauthorized {
+		token, _, err = requestOAuthToken(ctx, auth, true)
+	}
+	return token, err
+}
+
+func requestOAuthToken(ctx context.Context, auth queryAuth, credentialsInForm bool) (oauthToken, int, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if auth.scope != "" {
+		form.Set("scope", auth.scope)
+	}
+	if credentialsInForm {
+		form.Set("client_id", auth.clientID)
+		form.Set("client_secret", auth.clientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, auth.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return oauthToken{}, 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if !credentialsInForm {
+		req.SetBasicAuth(url.QueryEscape(auth.clientID), url.QueryEscape(auth.clientSecret))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return oauthToken{}, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return oauthToken{}, resp.StatusCode, err
+	}
+
+	var tokenResponse struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return oauthToken{}, resp.StatusCode, fmt.Errorf("token endpoint sent back %s that isn't JSON", resp.Status)
+	}
+	if tokenResponse.Error != "" {
+		message := tokenResponse.Error
+		if tokenResponse.ErrorDescription != "" {
+			message += ": " + tokenResponse.ErrorDescription
+		}
+		return oauthToken{}, resp.StatusCode, fmt.Errorf("token endpoint sent back %s (%s)", resp.Status, message)
+	}
+	if tokenResponse.AccessToken == "" {
+		return oauthToken{}, resp.StatusCode, fmt.Errorf("token endpoint sent back %s without an access_token", resp.Status)
+	}
+	token := oauthToken{accessToken: tokenResponse.AccessToken}
+	if tokenResponse.ExpiresIn > 0 {
+		token.expires = time.Now().Add(time.Duration(tokenResponse.ExpiresIn) * time.Second)
+	}
+	return token, resp.StatusCode, nil
+}
+
+type oauthTokenMsg struct {
+	requestID int
+	// query is the resolved query that needed the token, which is sent as it was rather than resolved again
+	query    QueryData
+	cacheKey string
+	token    oauthToken
+}
+
+// fetchOAuthTokenForQuery fetches a token for query, which is sent once it's back. It can be cancelled like a request.
+func fetchOAuthTokenForQuery(m *model, query QueryData) tea.Cmd {
+	timeout := m.requestTimeout()
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancelRequest = cancel
+	// kept so failing to get a token shows up in the history as this query failing
+	m.sentQuery = query
+	m.requestID++
+	m.inFlight = true
+	requestID := m.requestID
+
+	return func() tea.Msg {
+		defer cancel()
+		token, err := fetchOAuthToken(ctx, query.auth, timeout)
+		if err != nil {
+			return errMsg{requestID: requestID, err: fmt.Errorf("couldn't get an OAuth2 token: %w", err)}
+		}
+		return oauthTokenMsg{requestID: requestID, query: query, cacheKey: query.auth.oauthCacheKey(), token: token}
+	}
+}
+
+// describeOAuthToken says whether there's a token cached for auth, for the Auth tab.
+func describeOAuthToken(tokens map[string]oauthToken, auth queryAuth) string {
+	token, ok := tokens[auth.oauthCacheKey()]
+	switch {
+	case !ok:
+		return "(fetched when the query's sent)"
+	case !token.valid():
+		return "expired, a new one's fetched when the query's sent"
+	case token.expires.IsZero():
+		return "cached"
+	}
+	return fmt.Sprintf("cached, expires in %s", time.Until(token.expires).Round(time.Second))
+}
+
+// authPreview describes what auth adds to requests, masked, for the Headers and Params tabs. It's empty when auth
+// doesn't add anything to the given kind of list.
+func authPreview(auth queryAuth, inQuery bool) string {
+	if inQuery {
+		if auth.mode == authAPIKey && auth.keyInQuery && auth.keyName != "" {
+			return fmt.Sprintf("%s: %s", auth.keyName, maskSecret(auth.keyValue))
+		}
+		return ""
+	}
+	if auth.mode == authOAuth2 {
+		return "Authorization: Bearer (OAuth2 token)"
+	}
+	header, ok := auth.authHeader("")
+	if !ok {
+		return ""
+	}
+	value := maskSecret(header.value)
+	if strings.EqualFold(header.name, "Authorization") {
+		// Basic credentials are encoded, so variables in them can't be shown as is
+		value = maskAuthorization(header.value)
+		if auth.mode == authBasic {
+			value = "Basic " + maskedSecret
+		}
+	}
+	return fmt.Sprintf("%s: %s", header.name, value)
+}
+
+func buildAuthTabString(m model) string {
+	authTabString := ""
+	auth := m.currentQueryData.auth
+	for i, field := range authFields(auth.mode) {
+		value := field.get(auth)
+		if field.secret {
+			value = maskSecret(value)
+		}
+		fieldString := fmt.Sprintf(" %s: %s", field.label, value)
+		if i == m.focusedAuthField {
+			if m.uiState == UIStateEditingAuth {
+				authTabString += fmt.Sprintf(" %s: ", field.label) + m.textInput.View() + "\n"
+			} else {
+				focusedStyle := tabOpenStyle
+				if m.uiState == UIStateSelectingQuery {
+					focusedStyle = responseBodyStyle
+				}
+				authTabString += focusedStyle.Render(fieldString) + "\n"
+			}
+		} else {
+			authTabString += fieldString + "\n"
+		}
+	}
+	if auth.mode == authOAuth2 {
+		resolved := auth.resolve(m.variables())
+		authTabString += fmt.Sprintf(" token: %s\n", describeOAuthToken(m.oauthTokens, resolved))
+		authTabString += fmt.Sprintf("\n(%s forgets the cached token)\n", m.keys.ListDelete.Help().Key)
+	}
+	if auth.mode == authNone {
+		authTabString += "\n(no auth is added, set the mode to basic, bearer, apikey or oauth2 to add some)\n"
+	}
+	return lipgloss.Place(m.mainTabWidth, m.bodyHeight, lipgloss.Left, lipgloss.Top, responseBodyStyle.Render(authTabString),
+		lipgloss.WithWhitespaceBackground(responseBodyStyle.GetBackground())) + "\n"
+}
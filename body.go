@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	lipgloss "github.com/charmbracelet/lipgloss"
+)
+
+type bodyMode string
+
+const (
+	// bodyRaw sends the body as is with whatever Content-Type the headers say, which is how queries worked before
+	// there were body modes
+	bodyRaw       bodyMode = "raw"
+	bodyJSON      bodyMode = "json"
+	bodyText      bodyMode = "text"
+	bodyXML       bodyMode = "xml"
+	bodyForm      bodyMode = "form"
+	bodyMultipart bodyMode = "multipart"
+)
+
+var bodyModes = []bodyMode{bodyRaw, bodyJSON, bodyText, bodyXML, bodyForm, bodyMultipart}
+
+var bodyContentTypes = map[bodyMode]string{
+	bodyJSON: "application/json",
+	bodyText: "text/plain; charset=utf-8",
+	bodyXML:  "application/xml",
+	bodyForm: "application/x-www-form-urlencoded",
+}
+
+const formFieldPlaceholder = "Enter field (ex. name:alex)"
+const multipartFieldPlaceholder = "Enter field (ex. name:alex, or avatar:@~/Pictures/me.png for a file)"
+
+// nextBodyMode returns the mode after current, with queries from before there were modes starting at raw.
+func nextBodyMode(current bodyMode) bodyMode {
+	i := slices.Index(bodyModes, current)
+	return bodyModes[(i+1)%len(bodyModes)]
+}
+
+// usesFormFields is whether mode's body is the form fields rather than the text in the Body tab.
+func usesFormFields(mode bodyMode) bool {
+	return mode == bodyForm || mode == bodyMultipart
+}
+
+// parseFormField parses a field typed as name:value. Unlike headers, only the first colon splits the name from the
+// value, since values like URLs and times have colons in them.
+func parseFormField(s string) (QueryParamData, error) {
+	name, value, ok := strings.Cut(s, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return QueryParamData{}, fmt.Errorf("fields are name:value")
+	}
+	return QueryParamData{name: strings.TrimSpace(name), value: value}, nil
+}
+
+// formFilePath is the path of the file a multipart field sends, if it's a file part (ex. avatar:@me.png).
+func formFilePath(field QueryParamData) (string, bool) {
+	path, ok := strings.CutPrefix(field.value, "@")
+	if !ok {
+		return "", false
+	}
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, rest)
+		}
+	}
+	return path, true
+}
+
+// encodeBody turns query's body into the bytes that are sent for its mode, setting Content-Type to match. The result
+// is a raw query, so what's recorded in the history is exactly what was sent. The body type decides the Content-Type
+// for everything but raw bodies, replacing any the query's headers have, so a query switched from JSON to a form
+// doesn't send its form labelled as JSON.
+func encodeBody(query QueryData) (QueryData, error) {
+	contentType := bodyContentTypes[query.bodyMode]
+	switch query.bodyMode {
+	case bodyForm:
+		values := []string{}
+		for _, field := range query.formFields {
+			values = append(values, url.QueryEscape(field.name)+"="+url.QueryEscape(field.value))
+		}
+		query.body = []byte(strings.Join(values, "&"))
+	case bodyMultipart:
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		for _, field := range query.formFields {
+			if err := writeMultipartField(writer, field); err != nil {
+				return query, err
+			}
+		}
+		if err := writer.Close(); err != nil {
+			return query, err
+		}
+		query.body = body.Bytes()
+		contentType = writer.FormDataContentType()
+	}
+	query.bodyMode = bodyRaw
+	query.formFields = nil
+	if contentType == "" {
+		return query, nil
+	}
+
+	query.headers = slices.DeleteFunc(slices.Clone(query.headers), func(header HeaderData) bool {
+		return strings.EqualFold(header.name, "Content-Type")
+	})
+	query.headers = append(query.headers, HeaderData{name: "Content-Type", value: contentType})
+	return query, nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func writeMultipartField(writer *multipart.Writer, field QueryParamData) error {
+	path, isFile := formFilePath(field)
+	if !isFile {
+		return writer.WriteField(field.name, field.value)
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("couldn't read the file for %s: %w", field.name, err)
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(field.name), quoteEscaper.Replace(filepath.Base(path))))
+	header.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(contents)
+	return err
+}
+
+// bodyIsJSON is whether query's body is meant to be JSON, either because that's its body type or because it's a raw body
+// with a JSON Content-Type (ex. application/json or application/problem+json).
+func bodyIsJSON(query QueryData) bool {
+	if query.bodyMode == bodyJSON {
+		return true
+	}
+	if query.bodyMode != bodyRaw && query.bodyMode != "" {
+		return false
+	}
+	for _, header := range query.headers {
+		if strings.EqualFold(header.name, "Content-Type") && strings.Contains(strings.ToLower(header.value), "json") {
+			return true
+		}
+	}
+	return false
+}
+
+// maskPlaceholders replaces the {{name}} placeholders in body that aren't inside strings with whatever mask returns
+// for them, so bodies like {"id": {{id}}} can be checked and formatted before their variables are filled in.
+func maskPlaceholders(body string, mask func(i int, placeholder string) string) string {
+	var s strings.Builder
+	inString, escaped, next, masked := false, false, 0, 0
+	for _, loc := range variablePattern.FindAllStringIndex(body, -1) {
+		for _, c := range []byte(body[next:loc[0]]) {
+			switch {
+			case escaped:
+				escaped = false
+			case inString && c == '\\':
+				escaped = true
+			case c == '"':
+				inString = !inString
+			}
+		}
+		s.WriteString(body[next:loc[0]])
+		if inString {
+			s.WriteString(body[loc[0]:loc[1]])
+		} else {
+			s.WriteString(mask(masked, body[loc[0]:loc[1]]))
+			masked++
+		}
+		next = loc[1]
+	}
+	s.WriteString(body[next:])
+	return s.String()
+}
+
+// checkJSONBody returns why body isn't valid JSON, with the line and column the problem's at. Empty bodies are fine,
+// since plenty of requests with a JSON Content-Type don't send anything.
+func checkJSONBody(body string) error {
+	if strings.TrimSpace(body) == "" {
+		return nil
+	}
+	// placeholders are swapped for a string of the same length, so positions in the error are still right
+	masked := maskPlaceholders(body, func(_ int, placeholder string) string {
+		return `"` + strings.Repeat("_", len(placeholder)-2) + `"`
+	})
+	var value any
+	err := json.Unmarshal([]byte(masked), &value)
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return err
+	}
+	// Offset is just past the byte the problem was found at
+	at := max(0, int(syntaxErr.Offset)-1)
+	line := strings.Count(body[:at], "\n") + 1
+	column := utf8.RuneCountInString(body[strings.LastIndex(body[:at], "\n")+1:at]) + 1
+	return fmt.Errorf("line %d, column %d: %s", line, column, strings.TrimPrefix(syntaxErr.Error(), "json: "))
+}
+
+// formatJSONBody indents body two spaces at a time, or puts it all on one line when minify is true. Key order, number
+// formatting and placeholders outside strings are all kept as they were.
+func formatJSONBody(body string, minify bool) (string, error) {
+	if err := checkJSONBody(body); err != nil {
+		return "", err
+	}
+	placeholders := []string{}
+	masked := maskPlaceholders(body, func(i int, placeholder string) string {
+		placeholders = append(placeholders, placeholder)
+		return fmt.Sprintf(`"\ue000%d\ue000"`, i)
+	})
+	var formatted bytes.Buffer
+	var err error
+	if minify {
+		err = json.Compact(&formatted, []byte(masked))
+	} else {
+		err = json.Indent(&formatted, []byte(masked), "", "  ")
+	}
+	if err != nil {
+		return "", err
+	}
+	s := formatted.String()
+	for i, placeholder := range placeholders {
+		s = strings.Replace(s, fmt.Sprintf(`"\ue000%d\ue000"`, i), placeholder, 1)
+	}
+	return s, nil
+}
+
+// jsonBodyStatus is what the status bar says about the body being edited, when it's JSON.
+func (m model) jsonBodyStatus() string {
+	if !bodyIsJSON(*m.currentQueryData) {
+		return ""
+	}
+	if err := checkJSONBody(string(m.currentQueryData.body)); err != nil {
+		return "invalid JSON at " + err.Error()
+	}
+	return "valid JSON"
+}
+
+// toggleJSONFormat formats the body, or minifies it if it's already formatted. A blank body, which new queries start
+// with, is left alone.
+func (m *model) toggleJSONFormat() {
+	body := string(m.currentQueryData.body)
+	if strings.TrimSpace(body) == "" {
+		return
+	}
+	formatted, err := formatJSONBody(body, false)
+	if err == nil && formatted == body {
+		formatted, err = formatJSONBody(body, true)
+	}
+	if err != nil {
+		m.statusMessage = fmt.Sprintf("can't format the body, it isn't valid JSON (%s)", err)
+		return
+	}
+	m.currentQueryData.body = []byte(formatted)
+	m.textarea.SetValue(formatted)
+	m.persistWorkspace()
+}
+
+func describeBodyMode(mode bodyMode) string {
+	switch mode {
+	case bodyRaw, "":
+		return "raw (sent as is, with the Content-Type from the headers)"
+	case bodyForm:
+		return "form (application/x-www-form-urlencoded)"
+	case bodyMultipart:
+		return "multipart (multipart/form-data, @path sends a file)"
+	}
+	return fmt.Sprintf("%s (%s)", mode, bodyContentTypes[mode])
+}
+
+func buildBodyTabString(m model) string {
+	bodyTabString := tabClosedStyle.Render(fmt.Sprintf(" body: %s, %s to change ", describeBodyMode(m.currentQueryData.bodyMode), m.keys.CycleBodyMode.Help().Key)) + "\n"
+	if !usesFormFields(m.currentQueryData.bodyMode) {
+		bodyTabString += m.textarea.View()
+		return lipgloss.Place(m.mainTabWidth, m.bodyHeight, lipgloss.Left, lipgloss.Top, responseBodyStyle.Render(bodyTabString),
+			lipgloss.WithWhitespaceBackground(responseBodyStyle.GetBackground())) + "\n"
+	}
+
+	if len(m.currentQueryData.formFields) == 0 {
+		bodyTabString += fmt.Sprintf("(no fields will be sent, press %s/%s to add one)\n", m.keys.ListAdd.Help().Key, m.keys.ListNext.Help().Key)
+	}
+	for i, field := range m.currentQueryData.formFields {
+		fieldString := fmt.Sprintf(" %s: %s", field.name, field.value)
+		if i == m.focusedFormField {
+			if m.uiState == UIStateEditingFormField {
+				bodyTabString += m.textInput.View() + "\n"
+			} else {
+				focusedStyle := tabOpenStyle
+				if m.uiState == UIStateSelectingQuery {
+					focusedStyle = responseBodyStyle
+				}
+				bodyTabString += focusedStyle.Render(fieldString) + "\n"
+			}
+		} else {
+			bodyTabString += fieldString + "\n"
+		}
+	}
+	if m.uiState == UIStateAddingFormField {
+		bodyTabString += m.textInput.View() + "\n"
+	}
+	return lipgloss.Place(m.mainTabWidth, m.bodyHeight, lipgloss.Left, lipgloss.Top, responseBodyStyle.Render(bodyTabString),
+		lipgloss.WithWhitespaceBackground(responseBodyStyle.GetBackground())) + "\n"
+}
+
+func (m *model) startAddingFormField() {
+	m.uiState = UIStateAddingFormField
+	m.focusTextInputAndSetValue("")
+	m.textInput.Placeholder = formFieldPlaceholder
+	if m.currentQueryData.bodyMode == bodyMultipart {
+		m.textInput.Placeholder = multipartFieldPlaceholder
+	}
+}
+
+func (m *model) removeFocusedFormField() {
+	if len(m.currentQueryData.formFields) == 0 {
+		return
+	}
+	m.currentQueryData.formFields = slices.Delete(m.currentQueryData.formFields, m.focusedFormField, m.focusedFormField+1)
+	if m.focusedFormField == len(m.currentQueryData.formFields) {
+		m.focusedFormField = max(0, len(m.currentQueryData.formFields)-1)
+	}
+}
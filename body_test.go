@@ -0,0 +1,117 @@
+package main
+
+import "testing"
+
+func TestCheckJSONBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		// want is the error, or empty when the body should be valid
+		want string
+	}{
+		{name: "empty", body: "  \n"},
+		{name: "object", body: `{"a": [1, 2.5, true, null]}`},
+		{name: "placeholder as a value", body: `{"id": {{id}}, "ids": [{{first}}, {{ second }}]}`},
+		{name: "placeholder inside a string", body: `{"url": "{{baseUrl}}/users"}`},
+		{name: "placeholder as the whole body", body: "{{payload}}"},
+		{name: "placeholder after an escaped quote", body: `{"a": "say \"{{greeting}}\"", "b": {{b}}}`},
+		{
+			name: "error on the first line",
+			body: `{"a": 1,}`,
+			want: "line 1, column 9: invalid character '}' looking for beginning of object key string",
+		},
+		{
+			name: "error on a later line",
+			body: "{\n  \"a\": 1,\n  \"b\": tru\n}",
+			want: "line 3, column 11: invalid character '\\n' in literal true (expecting 'e')",
+		},
+		{
+			name: "column counts after a placeholder",
+			body: "{\n  \"id\": {{id}} \"name\": 1\n}",
+			want: "line 2, column 16: invalid character '\"' after object key:value pair",
+		},
+		{
+			name: "column counts characters, not bytes",
+			body: `{"naïve": x}`,
+			want: "line 1, column 11: invalid character 'x' looking for beginning of value",
+		},
+		{name: "unfinished", body: `{"a": [1, 2`, want: "line 1, column 11: unexpected end of JSON input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkJSONBody(tt.body)
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.want {
+				t.Errorf("checkJSONBody(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatJSONBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		minify  bool
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "indent",
+			body: `{"b":1,"a":[1.50,"x"],"c":{}}`,
+			want: "{\n  \"b\": 1,\n  \"a\": [\n    1.50,\n    \"x\"\n  ],\n  \"c\": {}\n}",
+		},
+		{
+			name:   "minify",
+			body:   "{\n  \"b\": 1,\n  \"a\": [\n    1.50\n  ]\n}",
+			minify: true,
+			want:   `{"b":1,"a":[1.50]}`,
+		},
+		{
+			name: "placeholders as values are kept",
+			body: `{"id":{{id}},"ids":[{{ first }},{{second}}]}`,
+			want: "{\n  \"id\": {{id}},\n  \"ids\": [\n    {{ first }},\n    {{second}}\n  ]\n}",
+		},
+		{
+			name:   "placeholders inside strings are kept",
+			body:   "{\n  \"url\": \"{{baseUrl}}/users/{{id}}\",\n  \"id\": {{id}}\n}",
+			minify: true,
+			want:   `{"url":"{{baseUrl}}/users/{{id}}","id":{{id}}}`,
+		},
+		{
+			name:   "the same placeholder more than once",
+			body:   `[{{id}}, {{id}}, "{{id}}"]`,
+			minify: true,
+			want:   `[{{id}},{{id}},"{{id}}"]`,
+		},
+		{name: "invalid", body: "{\n  \"a\": \n}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatJSONBody(tt.body, tt.minify)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("formatJSONBody(%q) = %q, want an error", tt.body, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("formatJSONBody(%q) returned error: %s", tt.body, err)
+			}
+			if got != tt.want {
+				t.Errorf("formatJSONBody(%q, %t) = %q, want %q", tt.body, tt.minify, got, tt.want)
+			}
+			// formatting one way then the other should get back to the same thing
+			back, err := formatJSONBody(got, !tt.minify)
+			if err != nil {
+				t.Fatalf("formatJSONBody(%q) returned error: %s", got, err)
+			}
+			if again, _ := formatJSONBody(back, tt.minify); again != got {
+				t.Errorf("round trip through %q gave %q, want %q", back, again, got)
+			}
+		})
+	}
+}
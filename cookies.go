@@ -0,0 +1,276 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	lipgloss "github.com/charmbracelet/lipgloss"
+)
+
+// jarCookie is a cookie as the jar keeps it, which is also how it's written to the cookies file. Cookies are told apart
+// by their domain, path and name, like browsers do.
+type jarCookie struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Domain string `json:"domain"`
+	Path   string `json:"path"`
+	// HostOnly cookies only go to Domain itself and not its subdomains, since the response that set them didn't say
+	// which domain they're for
+	HostOnly bool `json:"hostOnly,omitempty"`
+	// Expires is zero for session cookies, which are kept until the program's closed (or forever when they're saved)
+	Expires  time.Time `json:"expires,omitzero"`
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"httpOnly,omitempty"`
+}
+
+func (c jarCookie) expired(now time.Time) bool {
+	return !c.Expires.IsZero() && !c.Expires.After(now)
+}
+
+func (c jarCookie) sameAs(other jarCookie) bool {
+	return c.Domain == other.Domain && c.Path == other.Path && c.Name == other.Name
+}
+
+// cookieJar is an http.CookieJar that can list what's in it, which net/http/cookiejar can't, so cookies can be looked
+// at, edited and saved. There's no public suffix list, so while a response can't set a cookie for all of .com, it could
+// for all of .co.uk; that matters for browsers visiting sites they don't trust, not for requests you're writing yourself.
+type cookieJar struct {
+	// requests are sent from their own goroutines, so the jar's used from more than one at once
+	mu      sync.Mutex
+	cookies []jarCookie
+}
+
+func newCookieJar() *cookieJar {
+	return &cookieJar{cookies: []jarCookie{}}
+}
+
+// SetCookies stores the cookies a response to u set, following RFC 6265's rules for which domain and path they're for.
+func (j *cookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	host := strings.ToLower(u.Hostname())
+	for _, cookie := range cookies {
+		c := jarCookie{Name: cookie.Name, Value: cookie.Value, Domain: host, HostOnly: true, Path: cookie.Path,
+			Secure: cookie.Secure, HttpOnly: cookie.HttpOnly}
+		if cookie.Domain != "" {
+			domain := strings.ToLower(strings.TrimPrefix(cookie.Domain, "."))
+			// a response can only set cookies for its own domain or a parent of it, short of a top level domain, and IP
+			// addresses don't have parents
+			if host != domain && (!strings.HasSuffix(host, "."+domain) || !strings.Contains(domain, ".") || net.ParseIP(host) != nil) {
+				continue
+			}
+			c.Domain, c.HostOnly = domain, false
+		}
+		if !strings.HasPrefix(c.Path, "/") {
+			c.Path = defaultCookiePath(u.Path)
+		}
+		switch {
+		case cookie.MaxAge < 0:
+			c.Expires = now
+		case cookie.MaxAge > 0:
+			c.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		case !cookie.Expires.IsZero():
+			c.Expires = cookie.Expires
+		}
+
+		// cookies set to expire already are how servers delete them
+		i := slices.IndexFunc(j.cookies, c.sameAs)
+		switch {
+		case c.expired(now):
+			j.cookies = slices.DeleteFunc(j.cookies, c.sameAs)
+		case i >= 0:
+			// replacing a cookie keeps its place, so it's still sent before cookies set after it
+			j.cookies[i] = c
+		default:
+			j.cookies = append(j.cookies, c)
+		}
+	}
+}
+
+// Cookies returns the cookies to send with a request to u, most specific path first.
+func (j *cookieJar) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	host := strings.ToLower(u.Hostname())
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	matching := []jarCookie{}
+	for _, c := range j.cookies {
+		if c.expired(now) || (c.Secure && u.Scheme != "https") {
+			continue
+		}
+		if host != c.Domain && (c.HostOnly || !strings.HasSuffix(host, "."+c.Domain)) {
+			continue
+		}
+		if !cookiePathMatches(path, c.Path) {
+			continue
+		}
+		matching = append(matching, c)
+	}
+	slices.SortStableFunc(matching, func(a, b jarCookie) int { return len(b.Path) - len(a.Path) })
+
+	cookies := []*http.Cookie{}
+	for _, c := range matching {
+		cookies = append(cookies, &http.Cookie{Name: c.Name, Value: c.Value})
+	}
+	return cookies
+}
+
+// defaultCookiePath is the path a cookie's for when the response didn't say: the "directory" of the request's path.
+func defaultCookiePath(requestPath string) string {
+	i := strings.LastIndex(requestPath, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return requestPath[:i]
+}
+
+func cookiePathMatches(requestPath, cookiePath string) bool {
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return len(requestPath) == len(cookiePath) || strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/'
+}
+
+// list returns the cookies that haven't expired, sorted by domain, then path and name, which is how the Cookies tab
+// shows them.
+func (j *cookieJar) list() []jarCookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	j.cookies = slices.DeleteFunc(j.cookies, func(c jarCookie) bool { return c.expired(now) })
+	cookies := slices.Clone(j.cookies)
+	slices.SortFunc(cookies, func(a, b jarCookie) int {
+		return cmp.Or(cmp.Compare(a.Domain, b.Domain), cmp.Compare(a.Path, b.Path), cmp.Compare(a.Name, b.Name))
+	})
+	return cookies
+}
+
+// update replaces the cookie with the same domain, path and name as c.
+func (j *cookieJar) update(c jarCookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i := range j.cookies {
+		if j.cookies[i].sameAs(c) {
+			j.cookies[i] = c
+		}
+	}
+}
+
+func (j *cookieJar) remove(c jarCookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.cookies = slices.DeleteFunc(j.cookies, c.sameAs)
+}
+
+// cookiesPath puts the saved cookies next to the workspace they're for, ex. api.json's cookies are in api.cookies.json.
+func cookiesPath(workspacePath string) string {
+	return strings.TrimSuffix(workspacePath, filepath.Ext(workspacePath)) + ".cookies.json"
+}
+
+// loadCookieJar reads the cookies saved at path into a new jar. A missing file is an empty jar.
+func loadCookieJar(path string) (*cookieJar, error) {
+	jar := newCookieJar()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return jar, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &jar.cookies); err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %w", path, err)
+	}
+	return jar, nil
+}
+
+// save writes the jar to path. Cookies are often as good as a password, so only the user can read the file.
+func (j *cookieJar) save(path string) error {
+	data, err := json.MarshalIndent(j.list(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// saveCookieJar saves the jar next to the workspace if the workspace keeps its cookies.
+func (m *model) saveCookieJar() error {
+	if m.workspacePath == "" || !m.settings.persistCookies {
+		return nil
+	}
+	return m.cookieJar.save(cookiesPath(m.workspacePath))
+}
+
+func (m model) focusedJarCookie() (jarCookie, bool) {
+	cookies := m.cookieJar.list()
+	if m.focusedCookie < 0 || m.focusedCookie >= len(cookies) {
+		return jarCookie{}, false
+	}
+	return cookies[m.focusedCookie], true
+}
+
+func describeJarCookie(c jarCookie) string {
+	details := []string{"path " + c.Path}
+	if c.Expires.IsZero() {
+		details = append(details, "session")
+	} else {
+		details = append(details, "expires "+c.Expires.Local().Format(time.DateTime))
+	}
+	if !c.HostOnly {
+		details = append(details, "subdomains too")
+	}
+	if c.Secure {
+		details = append(details, "secure")
+	}
+	if c.HttpOnly {
+		details = append(details, "http only")
+	}
+	return strings.Join(details, ", ")
+}
+
+func buildCookiesTabString(m model) string {
+	cookiesTabString := "cookies are kept until the program's closed, turn on saving them in the Settings tab\n"
+	if m.settings.persistCookies && m.workspacePath != "" {
+		cookiesTabString = fmt.Sprintf("cookies are saved to %s\n", cookiesPath(m.workspacePath))
+	}
+	cookies := m.cookieJar.list()
+	if len(cookies) == 0 {
+		cookiesTabString += "\n(no cookies yet, they're added when responses set them)\n"
+	}
+	for i, c := range cookies {
+		if i == 0 || c.Domain != cookies[i-1].Domain {
+			cookiesTabString += "\n" + c.Domain + "\n"
+		}
+		cookieString := truncateString(fmt.Sprintf("  %s = %s  (%s)", c.Name, c.Value, describeJarCookie(c)), m.mainTabWidth-1)
+		if i == m.focusedCookie {
+			if m.uiState == UIStateEditingCookie {
+				cookiesTabString += fmt.Sprintf("  %s = ", c.Name) + m.textInput.View() + "\n"
+			} else {
+				focusedStyle := tabOpenStyle
+				if m.uiState == UIStateSelectingQuery {
+					focusedStyle = responseBodyStyle
+				}
+				cookiesTabString += focusedStyle.Render(cookieString) + "\n"
+			}
+		} else {
+			cookiesTabString += cookieString + "\n"
+		}
+	}
+	return lipgloss.Place(m.mainTabWidth, m.bodyHeight, lipgloss.Left, lipgloss.Top, responseBodyStyle.Render(cookiesTabString),
+		lipgloss.WithWhitespaceBackground(responseBodyStyle.GetBackground())) + "\n"
+}
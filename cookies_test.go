@@ -0,0 +1,228 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setCookies sets each Set-Cookie header line on jar as if a response to rawURL sent it.
+func setCookies(t *testing.T, jar *cookieJar, rawURL string, lines ...string) {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies := []*http.Cookie{}
+	for _, line := range lines {
+		cookie, err := http.ParseSetCookie(line)
+		if err != nil {
+			t.Fatalf("couldn't parse %q: %s", line, err)
+		}
+		cookies = append(cookies, cookie)
+	}
+	jar.SetCookies(u, cookies)
+}
+
+// sentCookies is the Cookie header jar would send to rawURL.
+func sentCookies(t *testing.T, jar *cookieJar, rawURL string) string {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pairs := []string{}
+	for _, cookie := range jar.Cookies(u) {
+		pairs = append(pairs, cookie.Name+"="+cookie.Value)
+	}
+	return strings.Join(pairs, "; ")
+}
+
+func TestCookieJarMatching(t *testing.T) {
+	tests := []struct {
+		name string
+		// set is the Set-Cookie lines each URL responded with, in order
+		set []struct {
+			url   string
+			lines []string
+		}
+		// want is the Cookie header sent to each URL
+		want map[string]string
+	}{
+		{
+			name: "host-only cookies only go to the host that set them",
+			set: []struct {
+				url   string
+				lines []string
+			}{{"http://example.com/", []string{"a=1"}}},
+			want: map[string]string{
+				"http://example.com/":     "a=1",
+				"http://EXAMPLE.com/x":    "a=1",
+				"http://www.example.com/": "",
+				"http://example.org/":     "",
+			},
+		},
+		{
+			name: "domain cookies go to subdomains too",
+			set: []struct {
+				url   string
+				lines []string
+			}{{"http://www.example.com/", []string{"a=1; Domain=example.com", "b=2; Domain=.Example.com"}}},
+			want: map[string]string{
+				"http://example.com/":       "a=1; b=2",
+				"http://a.b.example.com/":   "a=1; b=2",
+				"http://notexample.com/":    "",
+				"http://example.com.evil/":  "",
+				"http://www.example.org/":   "",
+				"http://localhost/":         "",
+				"https://www.example.com/x": "a=1; b=2",
+			},
+		},
+		{
+			name: "responses can't set cookies for other domains, top level domains or IP address parents",
+			set: []struct {
+				url   string
+				lines []string
+			}{
+				{"http://www.example.com/", []string{"a=1; Domain=other.com", "b=2; Domain=sub.www.example.com", "c=3; Domain=com"}},
+				{"http://10.0.0.1/", []string{"d=4; Domain=0.0.1", "e=5; Domain=10.0.0.1"}},
+				{"http://localhost/", []string{"f=6; Domain=localhost"}},
+			},
+			want: map[string]string{
+				"http://other.com/":           "",
+				"http://sub.www.example.com/": "",
+				"http://example.com/":         "",
+				"http://www.example.com/":     "",
+				"http://10.0.0.1/":            "e=5",
+				"http://1.0.0.1/":             "",
+				"http://localhost/":           "f=6",
+			},
+		},
+		{
+			name: "paths match on whole segments, longest first",
+			set: []struct {
+				url   string
+				lines []string
+			}{{"http://example.com/", []string{"root=1; Path=/", "api=2; Path=/api", "slash=3; Path=/api/"}}},
+			want: map[string]string{
+				"http://example.com":          "root=1",
+				"http://example.com/":         "root=1",
+				"http://example.com/api":      "api=2; root=1",
+				"http://example.com/api/":     "slash=3; api=2; root=1",
+				"http://example.com/api/x":    "slash=3; api=2; root=1",
+				"http://example.com/apix":     "root=1",
+				"http://example.com/v1/api/x": "root=1",
+			},
+		},
+		{
+			name: "cookies without a path are for the directory of the request",
+			set: []struct {
+				url   string
+				lines []string
+			}{
+				{"http://example.com/a/b/login", []string{"dir=1", "bad=2; Path=relative"}},
+				{"http://example.com/top", []string{"top=3"}},
+			},
+			want: map[string]string{
+				"http://example.com/a/b":   "dir=1; bad=2; top=3",
+				"http://example.com/a/b/c": "dir=1; bad=2; top=3",
+				"http://example.com/a":     "top=3",
+				"http://example.com/":      "top=3",
+			},
+		},
+		{
+			name: "secure cookies aren't sent over http",
+			set: []struct {
+				url   string
+				lines []string
+			}{{"https://example.com/", []string{"secure=1; Secure", "plain=2"}}},
+			want: map[string]string{
+				"https://example.com/": "secure=1; plain=2",
+				"http://example.com/":  "plain=2",
+			},
+		},
+		{
+			name: "expired cookies delete the ones they replace",
+			set: []struct {
+				url   string
+				lines []string
+			}{
+				{"http://example.com/", []string{"a=1", "b=2", "c=3", "d=4; Max-Age=3600", "e=5; Expires=Wed, 01 Jan 2200 00:00:00 GMT"}},
+				{"http://example.com/", []string{"a=gone; Max-Age=-1", "b=gone; Expires=Thu, 01 Jan 1970 00:00:00 GMT", "z=0; Max-Age=-1"}},
+			},
+			want: map[string]string{
+				"http://example.com/": "c=3; d=4; e=5",
+			},
+		},
+		{
+			name: "cookies are replaced by name, domain and path, keeping their place",
+			set: []struct {
+				url   string
+				lines []string
+			}{
+				{"http://www.example.com/", []string{"a=1", "b=1", "a=1; Path=/x", "a=1; Domain=example.com"}},
+				{"http://www.example.com/", []string{"a=2"}},
+			},
+			want: map[string]string{
+				"http://www.example.com/":  "a=2; b=1; a=1",
+				"http://www.example.com/x": "a=1; a=2; b=1; a=1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jar := newCookieJar()
+			for _, set := range tt.set {
+				setCookies(t, jar, set.url, set.lines...)
+			}
+			for rawURL, want := range tt.want {
+				if got := sentCookies(t, jar, rawURL); got != want {
+					t.Errorf("sent %q to %s, want %q", got, rawURL, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCookieJarExpiresWhileKept(t *testing.T) {
+	jar := newCookieJar()
+	setCookies(t, jar, "http://example.com/", "short=1; Max-Age=3600", "session=2")
+	// as if the hour's up
+	jar.cookies[0].Expires = time.Now().Add(-time.Second)
+	if got := sentCookies(t, jar, "http://example.com/"); got != "session=2" {
+		t.Errorf("sent %q, want only the session cookie", got)
+	}
+	if got := jar.list(); len(got) != 1 || got[0].Name != "session" {
+		t.Errorf("list() = %+v, want only the session cookie", got)
+	}
+}
+
+func TestCookieJarSaveAndLoad(t *testing.T) {
+	jar := newCookieJar()
+	setCookies(t, jar, "https://www.example.com/app/login",
+		"session=abc; HttpOnly; Secure", "prefs=dark; Domain=example.com; Path=/; Max-Age=3600")
+	path := filepath.Join(t.TempDir(), "ws.cookies.json")
+	if err := jar.save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadCookieJar(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equal := func(a, b jarCookie) bool { return a.sameAs(b) && a.Value == b.Value && a.Expires.Equal(b.Expires) }
+	if got, want := loaded.list(), jar.list(); !slices.EqualFunc(got, want, equal) {
+		t.Errorf("loaded %+v, want %+v", got, want)
+	}
+	if got := sentCookies(t, loaded, "https://www.example.com/app/x"); got != "session=abc; prefs=dark" {
+		t.Errorf("loaded jar sent %q", got)
+	}
+
+	missing, err := loadCookieJar(filepath.Join(t.TempDir(), "missing.cookies.json"))
+	if err != nil || len(missing.list()) != 0 {
+		t.Errorf("loading a missing file = %+v, %v, want an empty jar", missing.list(), err)
+	}
+}
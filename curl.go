@@ -0,0 +1,449 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+// curl options that don't change the request residentsleeper would send, split by whether they take a value so the
+// value doesn't get mistaken for the URL.
+var ignoredCurlFlags = []string{
+	"-L", "--location", "-k", "--insecure", "-s", "--silent", "-S", "--show-error", "-v", "--verbose",
+	"-i", "--include", "-f", "--fail", "--compressed", "-#", "--progress-bar", "-N", "--no-buffer",
+}
+var ignoredCurlOptions = [][2]string{
+	{"-o", "--output"}, {"-m", "--max-time"}, {"", "--connect-timeout"}, {"", "--retry"}, {"-w", "--write-out"}, {"", "--max-redirs"},
+}
+
+// parseCurlCommand turns a curl command line into a query. It understands the options people actually paste around
+// (method, headers, data, basic auth, user agent, cookies) and errors on anything else rather than guessing.
+func parseCurlCommand(command string) (QueryData, error) {
+	args, err := splitShellWords(command)
+	if err != nil {
+		return QueryData{}, err
+	}
+	if len(args) > 0 && args[0] == "curl" {
+		args = args[1:]
+	}
+
+	var (
+		method      HTTPMethod
+		rawURL      string
+		headers     []HeaderData
+		data        []string
+		dataInQuery bool
+		head        bool
+	)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		// value returns the option's value, which curl allows either attached (-XPOST) or as the next argument
+		value := func(short, long string) (string, bool, error) {
+			if arg == short || arg == long {
+				if i+1 >= len(args) {
+					return "", true, fmt.Errorf("curl option %s is missing a value", arg)
+				}
+				i++
+				return args[i], true, nil
+			}
+			if short != "" && len(arg) > len(short) && strings.HasPrefix(arg, short) && !strings.HasPrefix(arg, "--") {
+				return arg[len(short):], true, nil
+			}
+			return "", false, nil
+		}
+
+		if v, ok, err := value("-X", "--request"); ok {
+			if err != nil {
+				return QueryData{}, err
+			}
+			if method, err = parseHTTPMethod(v); err != nil {
+				return QueryData{}, err
+			}
+			continue
+		}
+		if v, ok, err := value("-H", "--header"); ok {
+			if err != nil {
+				return QueryData{}, err
+			}
+			name, headerValue, found := strings.Cut(v, ":")
+			if !found {
+				return QueryData{}, fmt.Errorf("header %q isn't in a name: value format", v)
+			}
+			headers = append(headers, HeaderData{name: strings.TrimSpace(name), value: strings.TrimSpace(headerValue)})
+			continue
+		}
+		if v, ok, err := value("-u", "--user"); ok {
+			if err != nil {
+				return QueryData{}, err
+			}
+			headers = append(headers, HeaderData{name: "Authorization", value: "Basic " + base64.StdEncoding.EncodeToString([]byte(v))})
+			continue
+		}
+		if v, ok, err := value("-A", "--user-agent"); ok {
+			if err != nil {
+				return QueryData{}, err
+			}
+			headers = append(headers, HeaderData{name: "User-Agent", value: v})
+			continue
+		}
+		if v, ok, err := value("-b", "--cookie"); ok {
+			if err != nil {
+				return QueryData{}, err
+			}
+			headers = append(headers, HeaderData{name: "Cookie", value: v})
+			continue
+		}
+		if v, ok, err := value("-e", "--referer"); ok {
+			if err != nil {
+				return QueryData{}, err
+			}
+			headers = append(headers, HeaderData{name: "Referer", value: v})
+			continue
+		}
+		if v, ok, err := value("", "--url"); ok {
+			if err != nil {
+				return QueryData{}, err
+			}
+			rawURL = v
+			continue
+		}
+		if v, ok, err := value("", "--json"); ok {
+			if err != nil {
+				return QueryData{}, err
+			}
+			data = append(data, v)
+			headers = append(headers, HeaderData{name: "Content-Type", value: "application/json"}, HeaderData{name: "Accept", value: "application/json"})
+			continue
+		}
+		if v, ok, err := value("", "--data-urlencode"); ok {
+			if err != nil {
+				return QueryData{}, err
+			}
+			// only the part after the first = is encoded, same as curl
+			if name, content, found := strings.Cut(v, "="); found {
+				data = append(data, name+"="+url.QueryEscape(content))
+			} else {
+				data = append(data, url.QueryEscape(v))
+			}
+			continue
+		}
+		if v, ok, err := value("", "--data-raw"); ok {
+			if err != nil {
+				return QueryData{}, err
+			}
+			data = append(data, v)
+			continue
+		}
+		isData := false
+		for _, dataOption := range [][2]string{{"-d", "--data"}, {"", "--data-binary"}, {"", "--data-ascii"}} {
+			v, ok, err := value(dataOption[0], dataOption[1])
+			if !ok {
+				continue
+			}
+			if err != nil {
+				return QueryData{}, err
+			}
+			// unlike --data-raw, these read the data from a file when it starts with @
+			if fileName, isFile := strings.CutPrefix(v, "@"); isFile {
+				contents, err := os.ReadFile(fileName)
+				if err != nil {
+					return QueryData{}, err
+				}
+				v = string(contents)
+			}
+			data = append(data, v)
+			isData = true
+			break
+		}
+		if isData {
+			continue
+		}
+		if arg == "-G" || arg == "--get" {
+			dataInQuery = true
+			continue
+		}
+		if arg == "-I" || arg == "--head" {
+			head = true
+			continue
+		}
+		isIgnored := false
+		for _, ignoredOption := range ignoredCurlOptions {
+			_, ok, err := value(ignoredOption[0], ignoredOption[1])
+			if !ok {
+				continue
+			}
+			if err != nil {
+				return QueryData{}, err
+			}
+			isIgnored = true
+			break
+		}
+		if isIgnored {
+			continue
+		}
+		if isIgnoredCurlFlag(arg) {
+			continue
+		}
+		if strings.HasPrefix(arg, "-") && len(arg) > 1 {
+			return QueryData{}, fmt.Errorf("unsupported curl option %s", arg)
+		}
+		if rawURL != "" {
+			return QueryData{}, fmt.Errorf("more than one URL given (%s and %s)", rawURL, arg)
+		}
+		rawURL = arg
+	}
+
+	if rawURL == "" {
+		return QueryData{}, errors.New("no URL found in curl command")
+	}
+	baseURL, rawQuery, _ := strings.Cut(rawURL, "?")
+	queryParams, err := parseQueryString(rawQuery)
+	if err != nil {
+		return QueryData{}, err
+	}
+
+	body := strings.Join(data, "&")
+	if dataInQuery && body != "" {
+		dataParams, err := parseQueryString(body)
+		if err != nil {
+			return QueryData{}, err
+		}
+		queryParams = append(queryParams, dataParams...)
+		body = ""
+	}
+
+	// curl's defaults when -X isn't given
+	if method == "" {
+		switch {
+		case head:
+			method = HEAD
+		case body != "":
+			method = POST
+		default:
+			method = GET
+		}
+	}
+	if body != "" && !hasHeader(headers, "Content-Type") {
+		headers = append(headers, HeaderData{name: "Content-Type", value: "application/x-www-form-urlencoded"})
+	}
+
+	if headers == nil {
+		headers = []HeaderData{}
+	}
+	return QueryData{
+		name:          curlQueryName(method, baseURL),
+		url:           baseURL,
+		body:          []byte(body),
+		headers:       headers,
+		queryParams:   queryParams,
+		requestMethod: method,
+	}, nil
+}
+
+func isIgnoredCurlFlag(arg string) bool {
+	if slices.Contains(ignoredCurlFlags, arg) {
+		return true
+	}
+	// short flags can be combined (ex. -sSL), which is fine as long as none of them take a value
+	if len(arg) > 2 && arg[0] == '-' && arg[1] != '-' {
+		for _, r := range arg[1:] {
+			if !slices.Contains(ignoredCurlFlags, "-"+string(r)) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// parseQueryString splits a query string into params in the order they're written, which url.ParseQuery doesn't keep.
+func parseQueryString(rawQuery string) ([]QueryParamData, error) {
+	queryParams := []QueryParamData{}
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		rawName, rawValue, _ := strings.Cut(pair, "=")
+		name, err := url.QueryUnescape(rawName)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query parameter %q: %w", pair, err)
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query parameter %q: %w", pair, err)
+		}
+		queryParams = append(queryParams, QueryParamData{name: name, value: value})
+	}
+	return queryParams, nil
+}
+
+func hasHeader(headers []HeaderData, name string) bool {
+	for _, header := range headers {
+		if strings.EqualFold(header.name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func curlQueryName(method HTTPMethod, rawURL string) string {
+	if parsed, err := url.Parse(rawURL); err == nil && parsed.Host != "" {
+		return fmt.Sprintf("%s %s%s", method, parsed.Host, parsed.Path)
+	}
+	return fmt.Sprintf("%s %s", method, rawURL)
+}
+
+// splitShellWords splits s into arguments the way a POSIX shell would, minus expansions. It handles single quotes,
+// double quotes, backslash escapes, bash's $'...' strings (which browsers use in "copy as curl") and backslash line
+// continuations, including ones whose newline was turned into a space when pasted into a text input.
+func splitShellWords(s string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inWord  bool
+	)
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes) && (runes[i+1] == '\n' || runes[i+1] == '\r'):
+			i++
+			if runes[i] == '\r' && i+1 < len(runes) && runes[i+1] == '\n' {
+				i++
+			}
+		case r == '\\' && !inWord && (i+1 == len(runes) || runes[i+1] == ' ' || runes[i+1] == '\t'):
+			// a lone backslash between arguments is a continuation that lost its newline
+		case r == '\\':
+			if i+1 < len(runes) {
+				i++
+				current.WriteRune(runes[i])
+			}
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				args = append(args, current.String())
+				current.Reset()
+				inWord = false
+			}
+		case r == '\'':
+			end := indexRune(runes, '\'', i+1)
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			current.WriteString(string(runes[i+1 : end]))
+			i = end
+			inWord = true
+		case r == '$' && i+1 < len(runes) && runes[i+1] == '\'':
+			end, err := writeANSICQuoted(&current, runes, i+2)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			inWord = true
+		case r == '"':
+			end, err := writeDoubleQuoted(&current, runes, i+1)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			inWord = true
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+func indexRune(runes []rune, r rune, from int) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// writeDoubleQuoted writes the contents of a double quoted string starting at runes[start] and returns the index of
+// the closing quote. Inside double quotes a backslash only escapes $, `, ", \ and newlines.
+func writeDoubleQuoted(b *strings.Builder, runes []rune, start int) (int, error) {
+	for i := start; i < len(runes); i++ {
+		switch {
+		case runes[i] == '"':
+			return i, nil
+		case runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("$`\"\\", runes[i+1]):
+			i++
+			b.WriteRune(runes[i])
+		case runes[i] == '\\' && i+1 < len(runes) && runes[i+1] == '\n':
+			i++
+		default:
+			b.WriteRune(runes[i])
+		}
+	}
+	return 0, errors.New("unterminated double quote")
+}
+
+// writeANSICQuoted writes the contents of a $'...' string starting at runes[start] and returns the index of the closing
+// quote.
+func writeANSICQuoted(b *strings.Builder, runes []rune, start int) (int, error) {
+	escapes := map[rune]string{'n': "\n", 't': "\t", 'r': "\r", '\\': "\\", '\'': "'", '"': "\""}
+	for i := start; i < len(runes); i++ {
+		switch {
+		case runes[i] == '\'':
+			return i, nil
+		case runes[i] == '\\' && i+1 < len(runes):
+			i++
+			if escaped, ok := escapes[runes[i]]; ok {
+				b.WriteString(escaped)
+			} else {
+				b.WriteRune('\\')
+				b.WriteRune(runes[i])
+			}
+		default:
+			b.WriteRune(runes[i])
+		}
+	}
+	return 0, errors.New("unterminated $'...' string")
+}
+
+// importCurlCommand implements `residentsleeper import-curl`, which reads a curl command from stdin and adds it to the
+// workspace as a new query.
+func importCurlCommand(args []string) error {
+	flags := flag.NewFlagSet("import-curl", flag.ExitOnError)
+	workspacePath := flags.String("workspace", "workspace.json", "workspace file to add the imported query to")
+	name := flags.String("name", "", "name for the imported query (defaults to the method and URL)")
+	flags.Parse(args)
+
+	command, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+	query, err := parseCurlCommand(string(command))
+	if err != nil {
+		return err
+	}
+	if *name != "" {
+		query.name = *name
+	}
+
+	// unlike the TUI, a missing workspace starts out empty here; nobody importing into a new file wants the demo queries
+	ws, err := readWorkspaceFile(*workspacePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	ws.Queries = append(ws.Queries, savedQueriesFromQueryData([]QueryData{query})...)
+	if err := saveWorkspace(*workspacePath, ws); err != nil {
+		return err
+	}
+	fmt.Printf("imported %q into %s\n", query.name, *workspacePath)
+	return nil
+}
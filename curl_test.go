@@ -0,0 +1,206 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitShellWords(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    []string
+		wantErr bool
+	}{
+		{name: "plain words", command: "curl -X POST http://localhost", want: []string{"curl", "-X", "POST", "http://localhost"}},
+		{name: "extra whitespace", command: "  curl\t -s  \n http://localhost ", want: []string{"curl", "-s", "http://localhost"}},
+		{name: "single quotes", command: `curl -H 'Accept: */*'`, want: []string{"curl", "-H", "Accept: */*"}},
+		{name: "double quotes", command: `curl -H "Accept: */*"`, want: []string{"curl", "-H", "Accept: */*"}},
+		{name: "double quotes inside single quotes", command: `-d '{"a": "b"}'`, want: []string{"-d", `{"a": "b"}`}},
+		{name: "single quotes inside double quotes", command: `-d "it's"`, want: []string{"-d", "it's"}},
+		{name: "escapes inside double quotes", command: `-d "say \"hi\" \$HOME \n"`, want: []string{"-d", `say "hi" $HOME \n`}},
+		{name: "quotes joined to a word", command: `-H'Accept: '"json"`, want: []string{"-HAccept: json"}},
+		{name: "escaped space", command: `a\ b c`, want: []string{"a b", "c"}},
+		{name: "ansi-c quotes", command: `-d $'line one\nit\'s\ttabbed'`, want: []string{"-d", "line one\nit's\ttabbed"}},
+		{name: "unknown ansi-c escape is kept", command: `$'\d'`, want: []string{`\d`}},
+		{name: "line continuation", command: "curl \\\n  -s \\\r\n  http://localhost", want: []string{"curl", "-s", "http://localhost"}},
+		{name: "continuation that lost its newline", command: `curl \ -s \ http://localhost \`, want: []string{"curl", "-s", "http://localhost"}},
+		{name: "empty quotes are an argument", command: `-d ''`, want: []string{"-d", ""}},
+		{name: "empty command", command: "", want: nil},
+		{name: "unterminated single quote", command: `-d 'abc`, wantErr: true},
+		{name: "unterminated double quote", command: `-d "abc`, wantErr: true},
+		{name: "unterminated ansi-c quote", command: `-d $'abc`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitShellWords(tt.command)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("splitShellWords(%q) = %q, want an error", tt.command, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("splitShellWords(%q) returned error: %s", tt.command, err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("splitShellWords(%q) = %q, want %q", tt.command, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCurlCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		command     string
+		wantMethod  HTTPMethod
+		wantURL     string
+		wantHeaders []HeaderData
+		wantParams  []QueryParamData
+		wantBody    string
+		wantErr     bool
+	}{
+		{
+			name:        "get",
+			command:     "curl http://localhost:8090/hello",
+			wantMethod:  GET,
+			wantURL:     "http://localhost:8090/hello",
+			wantHeaders: []HeaderData{},
+			wantParams:  []QueryParamData{},
+		},
+		{
+			name:        "query string is split into params in order",
+			command:     "curl 'http://localhost/users?sort=name&a%20b=c%2Fd'",
+			wantMethod:  GET,
+			wantURL:     "http://localhost/users",
+			wantHeaders: []HeaderData{},
+			wantParams:  []QueryParamData{{name: "sort", value: "name"}, {name: "a b", value: "c/d"}},
+		},
+		{
+			name:        "method and headers, attached or not",
+			command:     `curl -XPUT -H 'Accept: application/json' --header "X-Id:7" --url http://localhost/user/1`,
+			wantMethod:  PUT,
+			wantURL:     "http://localhost/user/1",
+			wantHeaders: []HeaderData{{name: "Accept", value: "application/json"}, {name: "X-Id", value: "7"}},
+			wantParams:  []QueryParamData{},
+		},
+		{
+			name:        "data makes it a form post",
+			command:     `curl -d 'a=1' --data-raw 'b=2' http://localhost/form`,
+			wantMethod:  POST,
+			wantURL:     "http://localhost/form",
+			wantHeaders: []HeaderData{{name: "Content-Type", value: "application/x-www-form-urlencoded"}},
+			wantParams:  []QueryParamData{},
+			wantBody:    "a=1&b=2",
+		},
+		{
+			name:        "data-urlencode only encodes after the first =",
+			command:     `curl --data-urlencode 'q=a b&c=d' --data-urlencode 'x y' http://localhost/form`,
+			wantMethod:  POST,
+			wantURL:     "http://localhost/form",
+			wantHeaders: []HeaderData{{name: "Content-Type", value: "application/x-www-form-urlencoded"}},
+			wantParams:  []QueryParamData{},
+			wantBody:    "q=a+b%26c%3Dd&x+y",
+		},
+		{
+			name:       "json sets content type and accept",
+			command:    `curl --json '{"name": "Dylan"}' http://localhost/users`,
+			wantMethod: POST,
+			wantURL:    "http://localhost/users",
+			wantHeaders: []HeaderData{
+				{name: "Content-Type", value: "application/json"},
+				{name: "Accept", value: "application/json"},
+			},
+			wantParams: []QueryParamData{},
+			wantBody:   `{"name": "Dylan"}`,
+		},
+		{
+			name:        "user is basic auth",
+			command:     "curl -u admin:hunter2 http://localhost/protected",
+			wantMethod:  GET,
+			wantURL:     "http://localhost/protected",
+			wantHeaders: []HeaderData{{name: "Authorization", value: "Basic YWRtaW46aHVudGVyMg=="}},
+			wantParams:  []QueryParamData{},
+		},
+		{
+			name:        "get moves data into the query",
+			command:     "curl -G -d 'a=1' -d 'b=2' http://localhost/users?c=3",
+			wantMethod:  GET,
+			wantURL:     "http://localhost/users",
+			wantHeaders: []HeaderData{},
+			wantParams:  []QueryParamData{{name: "c", value: "3"}, {name: "a", value: "1"}, {name: "b", value: "2"}},
+		},
+		{
+			name:        "head",
+			command:     "curl -I http://localhost/hello",
+			wantMethod:  HEAD,
+			wantURL:     "http://localhost/hello",
+			wantHeaders: []HeaderData{},
+			wantParams:  []QueryParamData{},
+		},
+		{
+			name: "browser copy as curl",
+			command: "curl 'http://localhost/users' \\\n  -H 'accept: */*' \\\n  -b 'session=abc' \\\n" +
+				"  --data-raw $'{\"note\":\"it\\'s\"}' \\\n  --compressed -sSL",
+			wantMethod:  POST,
+			wantURL:     "http://localhost/users",
+			wantHeaders: []HeaderData{{name: "accept", value: "*/*"}, {name: "Cookie", value: "session=abc"}, {name: "Content-Type", value: "application/x-www-form-urlencoded"}},
+			wantParams:  []QueryParamData{},
+			wantBody:    `{"note":"it's"}`,
+		},
+		{
+			name:        "ignored options skip their value",
+			command:     "curl -o out.txt --max-time 5 http://localhost/hello",
+			wantMethod:  GET,
+			wantURL:     "http://localhost/hello",
+			wantHeaders: []HeaderData{},
+			wantParams:  []QueryParamData{},
+		},
+		{
+			name:        "ignored options with their value attached",
+			command:     "curl -m5 -oout.txt -w'%{http_code}' -sSL http://localhost/hello",
+			wantMethod:  GET,
+			wantURL:     "http://localhost/hello",
+			wantHeaders: []HeaderData{},
+			wantParams:  []QueryParamData{},
+		},
+		{name: "no url", command: "curl -H 'Accept: */*'", wantErr: true},
+		{name: "two urls", command: "curl http://a http://b", wantErr: true},
+		{name: "unsupported option", command: "curl --proxy http://p http://a", wantErr: true},
+		{name: "missing value", command: "curl http://a -H", wantErr: true},
+		{name: "ignored option missing its value", command: "curl http://a --max-time", wantErr: true},
+		{name: "header without a colon", command: "curl -H 'Accept' http://a", wantErr: true},
+		{name: "invalid method", command: "curl -X 'GE T' http://a", wantErr: true},
+		{name: "bad quoting", command: "curl 'http://a", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := parseCurlCommand(tt.command)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseCurlCommand(%q) succeeded, want an error", tt.command)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseCurlCommand(%q) returned error: %s", tt.command, err)
+			}
+			if query.requestMethod != tt.wantMethod {
+				t.Errorf("method = %s, want %s", query.requestMethod, tt.wantMethod)
+			}
+			if query.url != tt.wantURL {
+				t.Errorf("url = %q, want %q", query.url, tt.wantURL)
+			}
+			if !slices.Equal(query.headers, tt.wantHeaders) {
+				t.Errorf("headers = %+v, want %+v", query.headers, tt.wantHeaders)
+			}
+			if !slices.Equal(query.queryParams, tt.wantParams) {
+				t.Errorf("query params = %+v, want %+v", query.queryParams, tt.wantParams)
+			}
+			if string(query.body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", query.body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	lipgloss "github.com/charmbracelet/lipgloss"
+)
+
+var diffAddedStyle = responseBodyStyle.Foreground(lipgloss.Color("#0ead69"))
+var diffRemovedStyle = responseBodyStyle.Foreground(lipgloss.Color("#f25c54"))
+var diffChangedStyle = responseBodyStyle.Foreground(lipgloss.Color("#ffd23f"))
+
+// past this many lines on both sides, the line diff gives up on finding what's in common and shows the middle of the
+// bodies as replaced, since the table it builds grows with both sides multiplied together
+const maxLineDiffCells = 4_000_000
+
+// values in a JSON diff are cut off at this many characters so a changed object doesn't take over the screen
+const maxDiffValueWidth = 80
+
+// diffSide is one of the two responses being diffed.
+type diffSide struct {
+	// id tells sides apart when their labels are the same: it's the response itself for a query's response, or when a
+	// history entry was recorded, to the nanosecond, for a history entry
+	id     any
+	label  string
+	status string
+	header http.Header
+	body   []byte
+}
+
+func diffSideFromResponse(label string, response *ResponseData) diffSide {
+	return diffSide{id: response, label: label, status: response.status, header: response.header, body: response.rawBody}
+}
+
+func diffSideFromHistory(entry historyEntry) diffSide {
+	return diffSide{
+		id:     entry.Time.UnixNano(),
+		label:  fmt.Sprintf("%s @ %s", entry.QueryName, formatHistoryTime(entry.Time)),
+		status: entry.Status,
+		header: entry.ResponseHeaders,
+		body:   entry.responseBody(),
+	}
+}
+
+type diffKind int
+
+const (
+	diffSame diffKind = iota
+	diffAdded
+	diffRemoved
+	diffChanged
+)
+
+func (kind diffKind) marker() string {
+	switch kind {
+	case diffAdded:
+		return "+"
+	case diffRemoved:
+		return "-"
+	case diffChanged:
+		return "~"
+	}
+	return " "
+}
+
+func (kind diffKind) style() lipgloss.Style {
+	switch kind {
+	case diffAdded:
+		return diffAddedStyle
+	case diffRemoved:
+		return diffRemovedStyle
+	case diffChanged:
+		return diffChangedStyle
+	}
+	return responseBodyStyle
+}
+
+// canDiffAsJSON is true when both sides parse as JSON, so they can be compared by path instead of by line.
+func canDiffAsJSON(base, other diffSide) bool {
+	return responseJSONTree(base.header.Get("Content-Type"), base.body) != nil &&
+		responseJSONTree(other.header.Get("Content-Type"), other.body) != nil
+}
+
+// buildDiffString renders the differences between base and other, structurally by JSON path when structural is set and
+// both are JSON, or line by line side by side in width columns otherwise.
+func buildDiffString(base, other diffSide, structural bool, width int) string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "- %s (%s)\n+ %s (%s)\n", base.label, base.status, other.label, other.status)
+	if base.status != other.status {
+		s.WriteString(diffChangedStyle.Render(fmt.Sprintf("~ status: %s → %s", base.status, other.status)) + "\n")
+	}
+	s.WriteString("\n")
+
+	if structural {
+		baseTree := responseJSONTree(base.header.Get("Content-Type"), base.body)
+		otherTree := responseJSONTree(other.header.Get("Content-Type"), other.body)
+		if baseTree != nil && otherTree != nil {
+			lines := []string{}
+			diffJSONNodes(baseTree, otherTree, &lines)
+			if len(lines) == 0 {
+				return s.String() + "(the bodies are the same)\n"
+			}
+			return s.String() + strings.Join(lines, "\n") + "\n"
+		}
+	}
+
+	baseBody, _ := formatResponseBody(base.header.Get("Content-Type"), base.body)
+	otherBody, _ := formatResponseBody(other.header.Get("Content-Type"), other.body)
+	rows := diffLines(splitLines(baseBody), splitLines(otherBody))
+	columnWidth := max(10, (width-3)/2)
+	for _, row := range rows {
+		left := padString(truncateString(row.left, columnWidth), columnWidth)
+		right := truncateString(row.right, columnWidth)
+		style := row.kind.style()
+		s.WriteString(style.Render(left+" "+row.kind.marker()+" "+right) + "\n")
+	}
+	return s.String()
+}
+
+// diffJSONNodes appends a line for every difference between base and other to lines. Objects are compared key by key
+// and arrays index by index, so only what actually changed is listed.
+func diffJSONNodes(base, other *jsonNode, lines *[]string) {
+	if base.kind == other.kind && base.kind == jsonObject {
+		for _, baseChild := range base.children {
+			otherChild := childWithKey(other, baseChild.key)
+			if otherChild == nil {
+				*lines = append(*lines, diffRemovedStyle.Render(fmt.Sprintf("- %s: %s", baseChild.path, compactJSON(baseChild))))
+				continue
+			}
+			diffJSONNodes(baseChild, otherChild, lines)
+		}
+		for _, otherChild := range other.children {
+			if childWithKey(base, otherChild.key) == nil {
+				*lines = append(*lines, diffAddedStyle.Render(fmt.Sprintf("+ %s: %s", otherChild.path, compactJSON(otherChild))))
+			}
+		}
+		return
+	}
+	if base.kind == other.kind && base.kind == jsonArray {
+		for i, baseChild := range base.children {
+			if i >= len(other.children) {
+				*lines = append(*lines, diffRemovedStyle.Render(fmt.Sprintf("- %s: %s", baseChild.path, compactJSON(baseChild))))
+				continue
+			}
+			diffJSONNodes(baseChild, other.children[i], lines)
+		}
+		for _, otherChild := range other.children[min(len(base.children), len(other.children)):] {
+			*lines = append(*lines, diffAddedStyle.Render(fmt.Sprintf("+ %s: %s", otherChild.path, compactJSON(otherChild))))
+		}
+		return
+	}
+	baseValue, otherValue := compactJSON(base), compactJSON(other)
+	if baseValue != otherValue {
+		*lines = append(*lines, diffChangedStyle.Render(fmt.Sprintf("~ %s: %s → %s", base.path, baseValue, otherValue)))
+	}
+}
+
+func childWithKey(node *jsonNode, key string) *jsonNode {
+	for _, child := range node.children {
+		if child.key == key {
+			return child
+		}
+	}
+	return nil
+}
+
+// compactJSON renders node on one line, cut off at maxDiffValueWidth.
+func compactJSON(node *jsonNode) string {
+	var s strings.Builder
+	writeCompactJSON(&s, node, maxDiffValueWidth)
+	return truncateString(s.String(), maxDiffValueWidth)
+}
+
+// writeCompactJSON writes node on one line, stopping once it's past limit characters unless limit is 0.
+func writeCompactJSON(s *strings.Builder, node *jsonNode, limit int) {
+	switch node.kind {
+	case jsonObject, jsonArray:
+		open, close := "{", "}"
+		if node.kind == jsonArray {
+			open, close = "[", "]"
+		}
+		s.WriteString(open)
+		for i, child := range node.children {
+			if i > 0 {
+				s.WriteString(",")
+			}
+			if child.hasKey {
+				s.WriteString(quoteJSONString(child.key) + ":")
+			}
+			writeCompactJSON(s, child, limit)
+			// nothing past the cutoff is shown, so there's no point writing out the rest of a big document
+			if limit > 0 && s.Len() > limit {
+				return
+			}
+		}
+		s.WriteString(close)
+	default:
+		value, _ := jsonScalar(node)
+		s.WriteString(value)
+	}
+}
+
+// diffRow is one row of a side by side diff. Changed rows pair a removed line with the line added in its place.
+type diffRow struct {
+	kind  diffKind
+	left  string
+	right string
+}
+
+// diffLines lines up base and other using their longest common subsequence, after taking off the lines they start and
+// end with in common so the table only has to cover the part that changed.
+func diffLines(base, other []string) []diffRow {
+	prefix := 0
+	for prefix < len(base) && prefix < len(other) && base[prefix] == other[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(base)-prefix && suffix < len(other)-prefix && base[len(base)-1-suffix] == other[len(other)-1-suffix] {
+		suffix++
+	}
+
+	rows := []diffRow{}
+	for _, line := range base[:prefix] {
+		rows = append(rows, diffRow{kind: diffSame, left: line, right: line})
+	}
+	rows = append(rows, diffMiddle(base[prefix:len(base)-suffix], other[prefix:len(other)-suffix])...)
+	for _, line := range base[len(base)-suffix:] {
+		rows = append(rows, diffRow{kind: diffSame, left: line, right: line})
+	}
+	return rows
+}
+
+func diffMiddle(base, other []string) []diffRow {
+	if len(base)*len(other) > maxLineDiffCells {
+		return pairChanges(base, other)
+	}
+	// lcs[i][j] is how many lines base[i:] and other[j:] have in common
+	lcs := make([][]int, len(base)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(other)+1)
+	}
+	for i := len(base) - 1; i >= 0; i-- {
+		for j := len(other) - 1; j >= 0; j-- {
+			if base[i] == other[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	rows := []diffRow{}
+	// removed and added lines are collected until the next line in common, so they can be shown next to each other
+	removed, added := []string{}, []string{}
+	flush := func() {
+		rows = append(rows, pairChanges(removed, added)...)
+		removed, added = []string{}, []string{}
+	}
+	i, j := 0, 0
+	for i < len(base) || j < len(other) {
+		switch {
+		case i < len(base) && j < len(other) && base[i] == other[j]:
+			flush()
+			rows = append(rows, diffRow{kind: diffSame, left: base[i], right: other[j]})
+			i++
+			j++
+		case j >= len(other) || (i < len(base) && lcs[i+1][j] >= lcs[i][j+1]):
+			removed = append(removed, base[i])
+			i++
+		default:
+			added = append(added, other[j])
+			j++
+		}
+	}
+	flush()
+	return rows
+}
+
+// pairChanges puts removed lines next to the lines added in their place as changes, with whatever's left over on
+// either side shown as only removed or only added.
+func pairChanges(removed, added []string) []diffRow {
+	rows := []diffRow{}
+	for i := 0; i < max(len(removed), len(added)); i++ {
+		switch {
+		case i < len(removed) && i < len(added):
+			rows = append(rows, diffRow{kind: diffChanged, left: removed[i], right: added[i]})
+		case i < len(removed):
+			rows = append(rows, diffRow{kind: diffRemoved, left: removed[i]})
+		default:
+			rows = append(rows, diffRow{kind: diffAdded, right: added[i]})
+		}
+	}
+	return rows
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(strings.ReplaceAll(s, "\t", "  "), "\n")
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, "\n")
+}
+
+func padString(s string, width int) string {
+	return s + strings.Repeat(" ", max(0, width-len([]rune(s))))
+}
+
+// markDiffSide marks side as the base of a diff, or diffs it against the base when one's already marked. Marking the
+// same thing twice unmarks it.
+func (m *model) markDiffSide(side diffSide) {
+	if m.diffBase == nil {
+		m.diffBase = &side
+		m.statusMessage = fmt.Sprintf("diffing from %s, press %s on another query or history entry to compare", side.label, m.keys.MarkDiff.Help().Key)
+		return
+	}
+	if m.diffBase.id == side.id {
+		m.diffBase = nil
+		m.statusMessage = "diff cancelled"
+		return
+	}
+	m.diffSides = [2]diffSide{*m.diffBase, side}
+	m.diffBase = nil
+	m.diffStructural = canDiffAsJSON(m.diffSides[0], m.diffSides[1])
+	m.uiState = UIStateShowingDiff
+	m.currentTab = TabResponse
+	m.showDiff()
+}
+
+func (m *model) showDiff() {
+	m.viewport.SetContent(buildDiffString(m.diffSides[0], m.diffSides[1], m.diffStructural, m.mainTabWidth))
+	m.viewport.GotoTop()
+}
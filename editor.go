@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// externalProgramMsg is sent when an editor or pager the program was suspended for exits. path is the temp file it
+// had open, which is read back into the body when readBack is set and removed either way.
+type externalProgramMsg struct {
+	path     string
+	readBack bool
+	err      error
+}
+
+// externalCommand is the command in envVar (which can have arguments, ex. EDITOR="code --wait"), or fallback when
+// it's not set.
+func externalCommand(envVar, fallback string) []string {
+	if args := strings.Fields(os.Getenv(envVar)); len(args) > 0 {
+		return args
+	}
+	return []string{fallback}
+}
+
+// bodyFileExtension gives the temp file an extension matching the body, so editors highlight it properly.
+func bodyFileExtension(query QueryData) string {
+	switch {
+	case bodyIsJSON(query):
+		return ".json"
+	case query.bodyMode == bodyXML:
+		return ".xml"
+	}
+	return ".txt"
+}
+
+// openInExternalProgram writes contents to a temp file and suspends the TUI to open it with the program in envVar.
+func openInExternalProgram(contents, extension, envVar, fallback string, readBack bool) tea.Cmd {
+	file, err := os.CreateTemp("", "residentsleeper-*"+extension)
+	if err != nil {
+		return func() tea.Msg { return externalProgramMsg{err: err} }
+	}
+	_, err = file.WriteString(contents)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return func() tea.Msg { return externalProgramMsg{err: err} }
+	}
+
+	args := append(externalCommand(envVar, fallback), file.Name())
+	cmd := exec.Command(args[0], args[1:]...)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return externalProgramMsg{path: file.Name(), readBack: readBack, err: err}
+	})
+}
+
+// editBodyInEditor opens the current query's body in $EDITOR, for payloads too big to edit comfortably in the textarea.
+func editBodyInEditor(m model) tea.Cmd {
+	return openInExternalProgram(string(m.currentQueryData.body), bodyFileExtension(*m.currentQueryData), "EDITOR", "vi", true)
+}
+
+// viewResponseInPager opens the current response's body in $PAGER. Nothing's read back, so the pager can't change it.
+func viewResponseInPager(m model) tea.Cmd {
+	extension := ".txt"
+	if m.shownResponse().jsonTree != nil {
+		extension = ".json"
+	}
+	return openInExternalProgram(m.shownResponse().body, extension, "PAGER", "less", false)
+}
+
+// finishExternalProgram reads the edited body back in once the editor exits. A failed editor leaves the body as it was,
+// since whatever's in the file might be half written.
+func (m *model) finishExternalProgram(msg externalProgramMsg) {
+	if msg.path != "" {
+		defer os.Remove(msg.path)
+	}
+	if msg.err != nil {
+		m.statusMessage = fmt.Sprintf("couldn't open the external program: %s", msg.err)
+		return
+	}
+	if !msg.readBack {
+		return
+	}
+	edited, err := os.ReadFile(msg.path)
+	if err != nil {
+		m.statusMessage = fmt.Sprintf("couldn't read the edited body: %s", err)
+		return
+	}
+	m.currentQueryData.body = edited
+	m.textarea.SetValue(string(edited))
+	m.persistWorkspace()
+	m.statusMessage = "body updated from the editor"
+}
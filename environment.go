@@ -0,0 +1,86 @@
+package main
+
+import (
+	"maps"
+	"regexp"
+	"slices"
+)
+
+// Environment is a named set of variables (ex. baseUrl for localhost vs. staging) that can be referenced from a query's
+// URL, headers, params and body as {{name}}.
+type Environment struct {
+	name      string
+	variables map[string]string
+}
+
+var variablePattern = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+
+// resolveVariables replaces every {{name}} in s with its value from vars. Placeholders without a value are left alone,
+// so a typo shows up in the request instead of silently turning into an empty string.
+func resolveVariables(s string, vars map[string]string) string {
+	return variablePattern.ReplaceAllStringFunc(s, func(placeholder string) string {
+		name := variablePattern.FindStringSubmatch(placeholder)[1]
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		return placeholder
+	})
+}
+
+// resolveQuery returns a copy of query with variables resolved everywhere they're allowed; query itself isn't modified,
+// so the placeholders stay in the saved query.
+func resolveQuery(query QueryData, vars map[string]string) QueryData {
+	resolved := copyQuery(query, query.name)
+	resolved.responseData = query.responseData
+	resolved.url = resolveVariables(query.url, vars)
+	resolved.body = []byte(resolveVariables(string(query.body), vars))
+	for i, header := range resolved.headers {
+		resolved.headers[i] = HeaderData{name: resolveVariables(header.name, vars), value: resolveVariables(header.value, vars)}
+	}
+	for i, param := range resolved.queryParams {
+		resolved.queryParams[i] = QueryParamData{name: resolveVariables(param.name, vars), value: resolveVariables(param.value, vars)}
+	}
+	for i, field := range resolved.formFields {
+		resolved.formFields[i] = QueryParamData{name: resolveVariables(field.name, vars), value: resolveVariables(field.value, vars)}
+	}
+	resolved.auth = query.auth.resolve(vars)
+	for i, source := range resolved.assertions {
+		resolved.assertions[i] = resolveVariables(source, vars)
+	}
+	return resolved
+}
+
+// variables returns the variables requests should be resolved with: the current environment's, with anything extracted
+// from responses on top.
+func (m model) variables() map[string]string {
+	if m.currentEnvironment < 0 || m.currentEnvironment >= len(m.environments) {
+		return mergeVariables(map[string]string{}, m.runtimeVariables)
+	}
+	return mergeVariables(m.environments[m.currentEnvironment].variables, m.runtimeVariables)
+}
+
+// mergeVariables returns a copy of base with override's values added in, replacing base's where both have a variable.
+func mergeVariables(base, override map[string]string) map[string]string {
+	merged := maps.Clone(base)
+	maps.Copy(merged, override)
+	return merged
+}
+
+func (m model) environmentName() string {
+	if m.currentEnvironment < 0 || m.currentEnvironment >= len(m.environments) {
+		return "no environment"
+	}
+	return m.environments[m.currentEnvironment].name
+}
+
+// cycleEnvironment switches to the next environment, going through "no environment" after the last one.
+func (m *model) cycleEnvironment() {
+	m.currentEnvironment += 1
+	if m.currentEnvironment >= len(m.environments) {
+		m.currentEnvironment = -1
+	}
+}
+
+func environmentIndex(environments []Environment, name string) int {
+	return slices.IndexFunc(environments, func(env Environment) bool { return env.name == name })
+}
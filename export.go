@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// exportAsCurl renders query as a curl command that can be pasted into a shell. query should already have its
+// variables resolved, since whoever runs the command won't have the environment.
+// Form and multipart bodies are left for curl to build from the fields, so files are read when the command's run
+// rather than pasted into it.
+func exportAsCurl(query QueryData) (string, error) {
+	req, err := buildRequest(query)
+	if err != nil {
+		return "", err
+	}
+	fields := query.formFields
+	formMode := query.bodyMode
+	if !usesFormFields(formMode) {
+		if query, err = encodeBody(query); err != nil {
+			return "", err
+		}
+		fields = nil
+	}
+
+	lines := []string{}
+	if query.requestMethod == GET && !hasBody(query) && len(fields) == 0 {
+		lines = append(lines, "curl "+shellQuote(req.URL.String()))
+	} else {
+		lines = append(lines, fmt.Sprintf("curl -X %s %s", query.requestMethod, shellQuote(req.URL.String())))
+	}
+	for _, header := range query.headers {
+		// curl sets the Content-Type for form fields itself, including the boundary it picks for multipart
+		if usesFormFields(formMode) && strings.EqualFold(header.name, "Content-Type") {
+			continue
+		}
+		lines = append(lines, "-H "+shellQuote(fmt.Sprintf("%s: %s", header.name, header.value)))
+	}
+	for _, field := range fields {
+		if formMode == bodyForm {
+			lines = append(lines, "--data-urlencode "+shellQuote(url.QueryEscape(field.name)+"="+field.value))
+		} else if path, isFile := formFilePath(field); isFile {
+			lines = append(lines, "-F "+shellQuote(field.name+"=@"+path))
+		} else {
+			// --form-string so values starting with @ or < aren't read from files
+			lines = append(lines, "--form-string "+shellQuote(field.name+"="+field.value))
+		}
+	}
+	if len(fields) == 0 && hasBody(query) {
+		lines = append(lines, "--data-raw "+shellQuote(string(query.body)))
+	}
+	return strings.Join(lines, " \\\n  "), nil
+}
+
+// exportAsGo renders query as a snippet of Go that sends the same request with net/http.
+func exportAsGo(query QueryData) (string, error) {
+	req, err := buildRequest(query)
+	if err != nil {
+		return "", err
+	}
+	if query, err = encodeBody(query); err != nil {
+		return "", err
+	}
+
+	body := "nil"
+	if hasBody(query) {
+		body = fmt.Sprintf("strings.NewReader(%s)", goStringLiteral(string(query.body)))
+	}
+
+	var s strings.Builder
+	fmt.Fprintf(&s, "req, err := http.NewRequest(%s, %s, %s)\n", strconv.Quote(string(query.requestMethod)), strconv.Quote(req.URL.String()), body)
+	s.WriteString("if err != nil {\n\tlog.Fatal(err)\n}\n")
+	for _, header := range query.headers {
+		fmt.Fprintf(&s, "req.Header.Set(%s, %s)\n", strconv.Quote(header.name), strconv.Quote(header.value))
+	}
+	s.WriteString("\nresp, err := http.DefaultClient.Do(req)\n")
+	s.WriteString("if err != nil {\n\tlog.Fatal(err)\n}\n")
+	s.WriteString("defer resp.Body.Close()\n")
+	return s.String(), nil
+}
+
+// hasBody is false for the whitespace-only bodies queries get by default, which aren't worth exporting.
+func hasBody(query QueryData) bool {
+	return strings.TrimSpace(string(query.body)) != ""
+}
+
+// shellQuote single quotes s for POSIX shells. Nothing is special inside single quotes except the quote itself, which
+// has to be closed, escaped and reopened.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// goStringLiteral prefers a raw string so JSON bodies stay readable, falling back to a quoted string when the body has
+// something a raw string can't hold.
+func goStringLiteral(s string) string {
+	if strconv.CanBackquote(s) {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
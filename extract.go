@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	lipgloss "github.com/charmbracelet/lipgloss"
+)
+
+const extractionPlaceholder = "Enter extraction (ex. token = json $.token, id = header X-Request-Id)"
+
+// extraction pulls a value out of a response into a variable, written as a line like `token = json $.token`. The
+// variable can then be used as {{token}} in later requests, so a login's token doesn't have to be copied over by hand.
+type extraction struct {
+	variable string
+	// source is where the value comes from: json, header or regex
+	source string
+	// target is the JSON path, header name or regex the value is found with
+	target string
+}
+
+// variable names can be anything a {{name}} placeholder can hold
+var extractionVariablePattern = regexp.MustCompile(`^[^{}\s]+$`)
+
+// parseExtraction parses one extraction. Everything after the source is the target, so regexes can have spaces in them
+// (ex. id = regex "id": (\d+)).
+func parseExtraction(source string) (extraction, error) {
+	variable, rest, ok := strings.Cut(source, "=")
+	variable = strings.TrimSpace(variable)
+	if !ok || variable == "" {
+		return extraction{}, fmt.Errorf("extractions start with the variable to set (ex. token = json $.token)")
+	}
+	if !extractionVariablePattern.MatchString(variable) {
+		return extraction{}, fmt.Errorf("%q can't be used as a variable name", variable)
+	}
+	from, target, _ := strings.Cut(strings.TrimSpace(rest), " ")
+	e := extraction{variable: variable, source: from, target: strings.TrimSpace(target)}
+	switch e.source {
+	case "json":
+		if _, err := parseJSONPath(e.target); err != nil {
+			return extraction{}, err
+		}
+	case "header":
+		if e.target == "" {
+			return extraction{}, fmt.Errorf("header extractions need a header name")
+		}
+	case "regex":
+		if e.target == "" {
+			return extraction{}, fmt.Errorf("regex extractions need a pattern")
+		}
+		if _, err := regexp.Compile(e.target); err != nil {
+			return extraction{}, fmt.Errorf("invalid regex: %w", err)
+		}
+	default:
+		return extraction{}, fmt.Errorf("%q isn't somewhere a value can come from, use json, header or regex", e.source)
+	}
+	return e, nil
+}
+
+func (e extraction) extract(response *ResponseData) (string, error) {
+	switch e.source {
+	case "json":
+		root := response.jsonTree
+		if root == nil {
+			var err error
+			if root, err = parseJSONTree(response.rawBody); err != nil {
+				return "", fmt.Errorf("body isn't JSON")
+			}
+		}
+		matches, _ := evaluateJSONPath(root, e.target)
+		if len(matches) == 0 {
+			return "", fmt.Errorf("nothing at %s", e.target)
+		}
+		// the first match is used, so $..id picks up the first id in the document
+		return jsonText(matches[0]), nil
+	case "header":
+		values, ok := response.header[http.CanonicalHeaderKey(e.target)]
+		if !ok || len(values) == 0 {
+			return "", fmt.Errorf("header isn't in the response")
+		}
+		return values[0], nil
+	case "regex":
+		pattern, err := regexp.Compile(e.target)
+		if err != nil {
+			return "", err
+		}
+		match := pattern.FindSubmatch(response.rawBody)
+		if match == nil {
+			return "", fmt.Errorf("regex doesn't match the body")
+		}
+		// the first group is the value when there is one, so the rest of the pattern can say what's around it
+		if len(match) > 1 {
+			return string(match[1]), nil
+		}
+		return string(match[0]), nil
+	}
+	return "", fmt.Errorf("unknown extraction")
+}
+
+// jsonText is node's value as it'd be put into a request: strings without their quotes and anything else as JSON.
+func jsonText(node *jsonNode) string {
+	if node.kind == jsonString {
+		return node.value.(string)
+	}
+	var s strings.Builder
+	writeCompactJSON(&s, node, 0)
+	return s.String()
+}
+
+type extractionResult struct {
+	variable string
+	value    string
+	err      error
+}
+
+// runExtractions runs every extraction in sources against response in order, setting vars for the ones that worked.
+// Variables that couldn't be extracted keep whatever they were set to before.
+func runExtractions(sources []string, response *ResponseData, vars map[string]string) []extractionResult {
+	results := []extractionResult{}
+	for _, source := range sources {
+		if strings.TrimSpace(source) == "" {
+			continue
+		}
+		e, err := parseExtraction(source)
+		if err != nil {
+			results = append(results, extractionResult{variable: source, err: err})
+			continue
+		}
+		value, err := e.extract(response)
+		if err == nil {
+			vars[e.variable] = value
+		}
+		results = append(results, extractionResult{variable: e.variable, value: value, err: err})
+	}
+	return results
+}
+
+// extractionSummary says which variables were set from a response, for the status line.
+func extractionSummary(results []extractionResult) string {
+	set, failed := []string{}, []string{}
+	for _, result := range results {
+		if result.err != nil {
+			failed = append(failed, fmt.Sprintf("%s (%s)", result.variable, result.err))
+		} else {
+			set = append(set, result.variable)
+		}
+	}
+	summary := []string{}
+	if len(set) > 0 {
+		summary = append(summary, "set "+strings.Join(set, ", "))
+	}
+	if len(failed) > 0 {
+		summary = append(summary, "couldn't set "+strings.Join(failed, ", "))
+	}
+	return strings.Join(summary, "; ")
+}
+
+func buildExtractTabString(m model) string {
+	extractTabString := ""
+	if len(m.currentQueryData.extractions) == 0 {
+		extractTabString += fmt.Sprintf("(no extractions, press %s to add one)\n", m.keys.ListAdd.Help().Key)
+	}
+	for i, source := range m.currentQueryData.extractions {
+		extractionString := " " + source
+		if i == m.focusedExtraction {
+			if m.uiState == UIStateEditingExtraction {
+				extractTabString += m.textInput.View() + "\n"
+			} else {
+				focusedStyle := tabOpenStyle
+				if m.uiState == UIStateSelectingQuery {
+					focusedStyle = responseBodyStyle
+				}
+				extractTabString += focusedStyle.Render(extractionString) + "\n"
+			}
+		} else {
+			extractTabString += extractionString + "\n"
+		}
+	}
+	if m.uiState == UIStateAddingExtraction {
+		extractTabString += m.textInput.View() + "\n"
+	}
+
+	extractTabString += "\nextracted variables (used over the environment's until the program's closed):\n"
+	if len(m.runtimeVariables) == 0 {
+		extractTabString += " (nothing extracted yet)\n"
+	}
+	names := []string{}
+	for name := range m.runtimeVariables {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		extractTabString += truncateString(fmt.Sprintf(" %s = %s", name, m.runtimeVariables[name]), m.mainTabWidth-1) + "\n"
+	}
+	return lipgloss.Place(m.mainTabWidth, m.bodyHeight, lipgloss.Left, lipgloss.Top, responseBodyStyle.Render(extractTabString),
+		lipgloss.WithWhitespaceBackground(responseBodyStyle.GetBackground())) + "\n"
+}
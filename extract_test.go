@@ -0,0 +1,110 @@
+package main
+
+import (
+	"maps"
+	"net/http"
+	"testing"
+)
+
+func TestParseExtraction(t *testing.T) {
+	tests := []struct {
+		source  string
+		want    extraction
+		wantErr bool
+	}{
+		{source: "token = json $.token", want: extraction{variable: "token", source: "json", target: "$.token"}},
+		{source: "  id=json .items[0].id  ", want: extraction{variable: "id", source: "json", target: ".items[0].id"}},
+		{source: "request.id = header X-Request-Id", want: extraction{variable: "request.id", source: "header", target: "X-Request-Id"}},
+		{source: `id = regex "id": (\d+)`, want: extraction{variable: "id", source: "regex", target: `"id": (\d+)`}},
+		{source: "eq = regex a=b", want: extraction{variable: "eq", source: "regex", target: "a=b"}},
+		{source: "", wantErr: true},
+		{source: "json $.token", wantErr: true},
+		{source: " = json $.token", wantErr: true},
+		{source: "my token = json $.token", wantErr: true},
+		{source: "{{token}} = json $.token", wantErr: true},
+		{source: "token =", wantErr: true},
+		{source: "token = body", wantErr: true},
+		{source: "token = json", wantErr: true},
+		{source: "token = json token", wantErr: true},
+		{source: "token = header", wantErr: true},
+		{source: "token = regex", wantErr: true},
+		{source: "token = regex (", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			got, err := parseExtraction(tt.source)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseExtraction(%q) = %+v, want an error", tt.source, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseExtraction(%q) returned error: %s", tt.source, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseExtraction(%q) = %+v, want %+v", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	response := &ResponseData{
+		header:  http.Header{"X-Request-Id": {"abc", "def"}},
+		rawBody: []byte(`{"token": "s3cr3t", "user": {"id": 7, "roles": ["a", "b"]}, "items": [{"id": 1}, {"id": 2}]}`),
+	}
+	tests := []struct {
+		source  string
+		want    string
+		wantErr bool
+	}{
+		{source: "token = json $.token", want: "s3cr3t"},
+		{source: "id = json $.user.id", want: "7"},
+		{source: "roles = json $.user.roles", want: `["a","b"]`},
+		{source: "id = json $..id", want: "7"},
+		{source: "last = json .items[-1].id", want: "2"},
+		{source: "missing = json $.nope", wantErr: true},
+		{source: "request = header x-request-id", want: "abc"},
+		{source: "missing = header X-Missing", wantErr: true},
+		{source: `token = regex "token": "([^"]+)"`, want: "s3cr3t"},
+		{source: `whole = regex "id": \d+`, want: `"id": 7`},
+		{source: "missing = regex nope", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			e, err := parseExtraction(tt.source)
+			if err != nil {
+				t.Fatalf("parseExtraction(%q) returned error: %s", tt.source, err)
+			}
+			got, err := e.extract(response)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("%q extracted %q, want an error", tt.source, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%q returned error: %s", tt.source, err)
+			}
+			if got != tt.want {
+				t.Errorf("%q extracted %q, want %q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunExtractionsKeepsOldValuesOnFailure(t *testing.T) {
+	response := &ResponseData{rawBody: []byte(`{"token": "new"}`)}
+	vars := map[string]string{"token": "old", "id": "old"}
+	results := runExtractions([]string{"token = json $.token", "", "id = json $.id", "bad"}, response, vars)
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3 (blank lines are skipped)", len(results))
+	}
+	if want := map[string]string{"token": "new", "id": "old"}; !maps.Equal(vars, want) {
+		t.Errorf("vars = %v, want %v", vars, want)
+	}
+	if results[1].err == nil || results[2].err == nil {
+		t.Errorf("want errors for the missing path and the extraction that doesn't parse, got %+v", results)
+	}
+}
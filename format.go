@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// responseFormatter pretty prints a response body. When it returns an error, the raw body is shown instead.
+type responseFormatter func(body []byte) (string, error)
+
+// responseFormatters are keyed on media type without parameters, so "application/json; charset=utf-8" still finds the
+// JSON formatter. Types using a structured syntax suffix (ex. application/problem+json) use their suffix's formatter,
+// see formatterForMediaType.
+var responseFormatters = map[string]responseFormatter{
+	"application/json":                  formatJSON,
+	"text/json":                         formatJSON,
+	"application/xml":                   formatXML,
+	"text/xml":                          formatXML,
+	"text/html":                         formatHTML,
+	"application/x-www-form-urlencoded": formatForm,
+	"text/plain":                        formatPlainText,
+}
+
+// the most of a binary body that gets hex dumped, since nobody is reading more than this in a terminal
+const maxHexDumpBytes = 4096
+
+// formatResponseBody pretty prints body based on contentType. If the body doesn't parse as what it claims to be, the
+// raw body is returned along with the error so the user can see both.
+func formatResponseBody(contentType string, body []byte) (string, error) {
+	if len(body) == 0 {
+		return "", nil
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return rawBodyString(body), fmt.Errorf("couldn't parse Content-Type %q: %w", contentType, err)
+	}
+
+	formatter := formatterForMediaType(mediaType)
+	if formatter == nil {
+		return rawBodyString(body), nil
+	}
+	formatted, err := formatter(body)
+	if err != nil {
+		return rawBodyString(body), fmt.Errorf("couldn't format body as %s: %w", mediaType, err)
+	}
+	return formatted, nil
+}
+
+func formatterForMediaType(mediaType string) responseFormatter {
+	if formatter, ok := responseFormatters[mediaType]; ok {
+		return formatter
+	}
+	// ex. application/problem+json or application/vnd.github+json
+	if i := strings.LastIndex(mediaType, "+"); i >= 0 {
+		if formatter, ok := responseFormatters["application/"+mediaType[i+1:]]; ok {
+			return formatter
+		}
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return formatPlainText
+	}
+	return nil
+}
+
+// rawBodyString shows a body as is, unless it isn't text, in which case a hex dump is more useful than mojibake.
+func rawBodyString(body []byte) string {
+	if utf8.Valid(body) {
+		return string(body)
+	}
+	if len(body) > maxHexDumpBytes {
+		return hex.Dump(body[:maxHexDumpBytes]) + fmt.Sprintf("... (%d more bytes not shown)\n", len(body)-maxHexDumpBytes)
+	}
+	return hex.Dump(body)
+}
+
+func formatJSON(body []byte) (string, error) {
+	var buffer bytes.Buffer
+	if err := json.Indent(&buffer, body, "", "\t"); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+func formatXML(body []byte) (string, error) {
+	// Token checks the document is well formed, but it also resolves namespace prefixes to URLs, so the printing is
+	// done from RawToken to keep the prefixes as written.
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return indentMarkup(xml.NewDecoder(bytes.NewReader(body)), nil)
+}
+
+// void HTML elements never have an end tag, so they can't increase the indent
+var htmlVoidElements = []string{"area", "base", "br", "col", "embed", "hr", "img", "input", "link", "meta", "param", "source", "track", "wbr"}
+
+func formatHTML(body []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+	return indentMarkup(decoder, htmlVoidElements)
+}
+
+// indentMarkup prints one tag per line, indented by depth. Elements that only contain text are kept on one line.
+func indentMarkup(decoder *xml.Decoder, voidElements []string) (string, error) {
+	var s strings.Builder
+	depth := 0
+	// whether the last thing written can have an end tag follow it on the same line
+	inlineEnd := false
+	newline := func() {
+		if s.Len() > 0 {
+			s.WriteString("\n")
+		}
+		s.WriteString(strings.Repeat("\t", depth))
+	}
+
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			newline()
+			s.WriteString("<" + markupName(token.Name))
+			for _, attr := range token.Attr {
+				s.WriteString(fmt.Sprintf(" %s=\"", markupName(attr.Name)))
+				xml.EscapeText(&s, []byte(attr.Value))
+				s.WriteString("\"")
+			}
+			s.WriteString(">")
+			if slices.Contains(voidElements, strings.ToLower(token.Name.Local)) {
+				inlineEnd = false
+				continue
+			}
+			depth++
+			inlineEnd = true
+		case xml.EndElement:
+			if slices.Contains(voidElements, strings.ToLower(token.Name.Local)) {
+				continue
+			}
+			depth = max(0, depth-1)
+			if !inlineEnd {
+				newline()
+			}
+			s.WriteString("</" + markupName(token.Name) + ">")
+			inlineEnd = false
+		case xml.CharData:
+			text := bytes.TrimSpace(token)
+			if len(text) == 0 {
+				continue
+			}
+			if !inlineEnd {
+				newline()
+			}
+			xml.EscapeText(&s, text)
+		case xml.Comment:
+			newline()
+			s.WriteString("<!--" + string(token) + "-->")
+			inlineEnd = false
+		case xml.ProcInst:
+			newline()
+			s.WriteString(fmt.Sprintf("<?%s %s?>", token.Target, token.Inst))
+			inlineEnd = false
+		case xml.Directive:
+			newline()
+			s.WriteString("<!" + string(token) + ">")
+			inlineEnd = false
+		}
+	}
+	if s.Len() == 0 {
+		return "", errors.New("no markup found")
+	}
+	return s.String() + "\n", nil
+}
+
+func markupName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+func formatForm(body []byte) (string, error) {
+	params, err := parseQueryString(strings.TrimSpace(string(body)))
+	if err != nil {
+		return "", err
+	}
+	formString := ""
+	for _, param := range params {
+		formString += fmt.Sprintf("%s = %s\n", param.name, param.value)
+	}
+	return formString, nil
+}
+
+func formatPlainText(body []byte) (string, error) {
+	if !utf8.Valid(body) {
+		return "", errors.New("body isn't valid UTF-8")
+	}
+	return strings.ReplaceAll(string(body), "\r\n", "\n"), nil
+}
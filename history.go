@@ -0,0 +1,418 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	lipgloss "github.com/charmbracelet/lipgloss"
+)
+
+// request and response bodies bigger than this are cut off in the history file, which would otherwise grow by an
+// upload's or a download's size each time one is sent
+const maxHistoryBodyBytes = 1 << 20
+
+// maxHistoryLineBytes is the longest line the history file is read with, which is room for both bodies even with every
+// byte escaped. Longer lines can't have been written by residentsleeper, so they're skipped.
+const maxHistoryLineBytes = 16 * maxHistoryBodyBytes
+
+// the history keeps the newest maxHistoryEntries entries. The file's only rewritten without the older ones once it's
+// historyPruneSlack entries over, so it isn't rewritten on every request.
+const (
+	maxHistoryEntries = 500
+	historyPruneSlack = 50
+)
+
+// historyEntry is one sent request and what came back, as it's written to the history file. Everything is recorded
+// as it was sent, with variables resolved and auth applied, so replaying it doesn't depend on the environment; that
+// means the file has credentials in it, which is why only the user can read it. Bodies that aren't UTF-8 (ex. images
+// or multipart bodies with files) are base64 encoded, since JSON strings can't hold them.
+type historyEntry struct {
+	Time             time.Time      `json:"time"`
+	QueryName        string         `json:"queryName"`
+	Method           string         `json:"method"`
+	URL              string         `json:"url"`
+	Headers          []savedPair    `json:"headers,omitempty"`
+	Body             string         `json:"body,omitempty"`
+	BodyBase64       bool           `json:"bodyBase64,omitempty"`
+	RequestTruncated bool           `json:"requestBodyTruncated,omitempty"`
+	Timeout          duration       `json:"timeout,omitempty"`
+	MaxRedirects     int            `json:"maxRedirects,omitempty"`
+	SkipCookieJar    bool           `json:"skipCookieJar,omitempty"`
+	Status           string         `json:"status,omitempty"`
+	Error            string         `json:"error,omitempty"`
+	ResponseHeaders  http.Header    `json:"responseHeaders,omitempty"`
+	ResponseBody     string         `json:"responseBody,omitempty"`
+	ResponseBase64   bool           `json:"responseBodyBase64,omitempty"`
+	BodyTruncated    bool           `json:"bodyTruncated,omitempty"`
+	Timing           *historyTiming `json:"timing,omitempty"`
+}
+
+type historyTiming struct {
+	DNSLookup        duration `json:"dnsLookup"`
+	Connect          duration `json:"connect"`
+	TLSHandshake     duration `json:"tlsHandshake"`
+	TimeToFirstByte  duration `json:"timeToFirstByte"`
+	Download         duration `json:"download"`
+	SimulatedLatency duration `json:"simulatedLatency"`
+	Total            duration `json:"total"`
+}
+
+// historyPath puts the history next to the workspace it's for, ex. api.json's history is api.history.jsonl.
+func historyPath(workspacePath string) string {
+	return strings.TrimSuffix(workspacePath, filepath.Ext(workspacePath)) + ".history.jsonl"
+}
+
+// loadHistory reads the newest entries in the history file at path, oldest first, along with how many lines were
+// skipped. A missing file is an empty history, and lines that don't parse (ex. from being cut off by a crash mid-write)
+// or are too long are skipped rather than losing the rest.
+func loadHistory(path string) ([]historyEntry, int, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []historyEntry{}, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	history := []historyEntry{}
+	skipped := 0
+	reader := bufio.NewReader(file)
+	for {
+		line, tooLong, err := readHistoryLine(reader)
+		if err != nil && err != io.EOF {
+			return nil, 0, err
+		}
+		if len(bytes.TrimSpace(line)) > 0 || tooLong {
+			var entry historyEntry
+			if tooLong || json.Unmarshal(line, &entry) != nil {
+				skipped++
+			} else {
+				history = append(history, entry)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if len(history) > maxHistoryEntries {
+		history = history[len(history)-maxHistoryEntries:]
+	}
+	return history, skipped, nil
+}
+
+// readHistoryLine reads the next line from reader. Lines longer than maxHistoryLineBytes are read past without being
+// kept, with tooLong set instead.
+func readHistoryLine(reader *bufio.Reader) (line []byte, tooLong bool, err error) {
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if !tooLong && len(line)+len(chunk) > maxHistoryLineBytes {
+			line, tooLong = nil, true
+		}
+		if !tooLong {
+			line = append(line, chunk...)
+		}
+		if err != bufio.ErrBufferFull {
+			return line, tooLong, err
+		}
+	}
+}
+
+// appendHistory adds entry to the end of the history file at path. The file is only appended to between prunes, so a
+// request sent while something else is reading the history can't clobber it.
+func appendHistory(path string, entry historyEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	// files from before the history was kept private are fixed up too
+	if err := file.Chmod(0o600); err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// writeHistory replaces the history file at path with history. It's written to a temp file that's renamed over the
+// old one, so a crash part way through can't lose the whole history.
+func writeHistory(path string, history []historyEntry) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	writer := bufio.NewWriter(file)
+	for _, entry := range history {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		writer.Write(append(line, '\n'))
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// encodeHistoryBody is body as it's saved in a history entry, and whether that's base64.
+func encodeHistoryBody(body []byte) (string, bool) {
+	if utf8.Valid(body) {
+		return string(body), false
+	}
+	return base64.StdEncoding.EncodeToString(body), true
+}
+
+func decodeHistoryBody(body string, isBase64 bool) []byte {
+	if !isBase64 {
+		return []byte(body)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return []byte(body)
+	}
+	return decoded
+}
+
+func (entry historyEntry) requestBody() []byte {
+	return decodeHistoryBody(entry.Body, entry.BodyBase64)
+}
+
+func (entry historyEntry) responseBody() []byte {
+	return decodeHistoryBody(entry.ResponseBody, entry.ResponseBase64)
+}
+
+// newHistoryEntry records query, which should already be resolved, along with its response or the error sending it.
+func newHistoryEntry(query QueryData, response *ResponseData, err error) historyEntry {
+	entry := historyEntry{
+		Time:      time.Now(),
+		QueryName: query.name,
+		Method:    string(query.requestMethod),
+		URL:       query.url,
+		// the query's own settings, so a replay is sent the same way (where they're unset, it'll use the workspace's)
+		Timeout:       duration(query.timeout),
+		MaxRedirects:  query.maxRedirects,
+		SkipCookieJar: query.skipCookieJar,
+	}
+	requestBody := query.body
+	if len(requestBody) > maxHistoryBodyBytes {
+		requestBody = requestBody[:maxHistoryBodyBytes]
+		entry.RequestTruncated = true
+	}
+	entry.Body, entry.BodyBase64 = encodeHistoryBody(requestBody)
+	// the URL is recorded with its query params, since that's what the server saw
+	if req, err := buildRequest(query); err == nil {
+		entry.URL = req.URL.String()
+	}
+	for _, header := range query.headers {
+		entry.Headers = append(entry.Headers, savedPair{Name: header.name, Value: header.value})
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+
+	entry.Status = response.status
+	entry.ResponseHeaders = response.header
+	body := response.rawBody
+	if len(body) > maxHistoryBodyBytes {
+		body = body[:maxHistoryBodyBytes]
+		entry.BodyTruncated = true
+	}
+	entry.ResponseBody, entry.ResponseBase64 = encodeHistoryBody(body)
+	entry.Timing = &historyTiming{
+		DNSLookup:        duration(response.timing.dnsLookup),
+		Connect:          duration(response.timing.connect),
+		TLSHandshake:     duration(response.timing.tlsHandshake),
+		TimeToFirstByte:  duration(response.timing.timeToFirstByte),
+		Download:         duration(response.timing.download),
+		SimulatedLatency: duration(response.timing.simulatedLatency),
+		Total:            duration(response.timing.total),
+	}
+	return entry
+}
+
+// queryData turns entry back into a query. Query params are split back out of the URL so they can be edited like any
+// other query's.
+func (entry historyEntry) queryData(name string) QueryData {
+	query := QueryData{
+		name:          name,
+		url:           entry.URL,
+		body:          entry.requestBody(),
+		headers:       []HeaderData{},
+		queryParams:   []QueryParamData{},
+		requestMethod: HTTPMethod(entry.Method),
+		timeout:       time.Duration(entry.Timeout),
+		maxRedirects:  entry.MaxRedirects,
+		skipCookieJar: entry.SkipCookieJar,
+	}
+	if u, err := url.Parse(entry.URL); err == nil && u.RawQuery != "" {
+		if params, err := parseQueryString(u.RawQuery); err == nil {
+			query.queryParams = params
+			u.RawQuery = ""
+			query.url = u.String()
+		}
+	}
+	for _, header := range entry.Headers {
+		query.headers = append(query.headers, HeaderData{name: header.Name, value: header.Value})
+	}
+	return query
+}
+
+// recordHistory saves query and how it went to the history, both in memory and in the history file.
+func (m *model) recordHistory(query QueryData, response *ResponseData, err error) {
+	entry := newHistoryEntry(query, response, err)
+	m.history = append(m.history, entry)
+	if m.workspacePath == "" {
+		return
+	}
+	path := historyPath(m.workspacePath)
+	saveErr := appendHistory(path, entry)
+	if saveErr == nil && len(m.history) > maxHistoryEntries+historyPruneSlack {
+		m.history = slices.Clone(m.history[len(m.history)-maxHistoryEntries:])
+		saveErr = writeHistory(path, m.history)
+	}
+	if saveErr != nil {
+		m.statusMessage = fmt.Sprintf("couldn't save history: %s", saveErr)
+	}
+}
+
+// focusedHistoryEntry is the entry focused in the History tab, which lists the newest entries first.
+func (m model) focusedHistoryEntry() (historyEntry, bool) {
+	if m.focusedHistory < 0 || m.focusedHistory >= len(m.history) {
+		return historyEntry{}, false
+	}
+	return m.history[len(m.history)-1-m.focusedHistory], true
+}
+
+func formatHistoryTime(t time.Time) string {
+	now := time.Now()
+	if t.YearDay() == now.YearDay() && t.Year() == now.Year() {
+		return t.Local().Format(time.TimeOnly)
+	}
+	return t.Local().Format("Jan 2 15:04:05")
+}
+
+func historyEntryOutcome(entry historyEntry) string {
+	if entry.Error != "" {
+		return "error: " + entry.Error
+	}
+	outcome := entry.Status
+	if entry.Timing != nil {
+		outcome += fmt.Sprintf(" in %s", time.Duration(entry.Timing.Total).Round(time.Millisecond))
+	}
+	return outcome
+}
+
+// buildHistoryEntryString shows everything recorded about entry, for reading through in the viewport.
+func buildHistoryEntryString(entry historyEntry) string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "%s %s\n", entry.Method, entry.URL)
+	fmt.Fprintf(&s, "sent %s from %q\n", entry.Time.Local().Format(time.DateTime), entry.QueryName)
+	for _, header := range entry.Headers {
+		fmt.Fprintf(&s, "%s: %s\n", header.Name, header.Value)
+	}
+	if entry.BodyBase64 {
+		fmt.Fprintf(&s, "\n(%d byte body that isn't text)\n", len(entry.requestBody()))
+	} else if strings.TrimSpace(entry.Body) != "" {
+		fmt.Fprintf(&s, "\n%s\n", strings.TrimSuffix(entry.Body, "\n"))
+	}
+	if entry.RequestTruncated {
+		fmt.Fprintf(&s, "... (body cut off after %d bytes)\n", maxHistoryBodyBytes)
+	}
+
+	fmt.Fprintf(&s, "\n%s\n", historyEntryOutcome(entry))
+	if entry.Error != "" {
+		return s.String()
+	}
+	if timing := entry.Timing; timing != nil {
+		fmt.Fprintf(&s, "dns %s, connect %s, tls %s, first byte %s, download %s\n", formatPhaseDuration(time.Duration(timing.DNSLookup)),
+			formatPhaseDuration(time.Duration(timing.Connect)), formatPhaseDuration(time.Duration(timing.TLSHandshake)),
+			formatPhaseDuration(time.Duration(timing.TimeToFirstByte)), formatPhaseDuration(time.Duration(timing.Download)))
+	}
+	names := []string{}
+	for name := range entry.ResponseHeaders {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		for _, value := range entry.ResponseHeaders[name] {
+			fmt.Fprintf(&s, "%s: %s\n", name, value)
+		}
+	}
+	if entry.ResponseBody != "" {
+		body, err := formatResponseBody(entry.ResponseHeaders.Get("Content-Type"), entry.responseBody())
+		if err != nil && !entry.BodyTruncated {
+			fmt.Fprintf(&s, "\n(%s, showing the body as is)", err)
+		}
+		fmt.Fprintf(&s, "\n%s\n", strings.TrimSuffix(body, "\n"))
+	}
+	if entry.BodyTruncated {
+		fmt.Fprintf(&s, "... (body cut off after %d bytes)\n", maxHistoryBodyBytes)
+	}
+	return s.String()
+}
+
+func buildHistoryTabString(m model) string {
+	historyTabString := ""
+	if m.uiState == UIStateShowingHistoryEntry {
+		historyTabString = m.viewport.View()
+	} else if len(m.history) == 0 {
+		historyTabString = "(no requests sent yet)\n"
+	} else {
+		// only as many entries as fit are shown, scrolled along with the focused entry
+		rows := max(1, m.bodyHeight)
+		start := max(0, m.focusedHistory-rows+1)
+		for i := start; i < min(len(m.history), start+rows); i++ {
+			entry := m.history[len(m.history)-1-i]
+			entryString := fmt.Sprintf(" %s  %-7s %s  %s  %s", formatHistoryTime(entry.Time), entry.Method, historyEntryOutcome(entry), entry.QueryName, entry.URL)
+			entryString = truncateString(entryString, m.mainTabWidth-1)
+			if i == m.focusedHistory {
+				focusedStyle := tabOpenStyle
+				if m.uiState == UIStateSelectingQuery {
+					focusedStyle = responseBodyStyle
+				}
+				historyTabString += focusedStyle.Render(entryString) + "\n"
+			} else {
+				historyTabString += entryString + "\n"
+			}
+		}
+	}
+	return lipgloss.Place(m.mainTabWidth, m.bodyHeight, lipgloss.Left, lipgloss.Top, responseBodyStyle.Render(historyTabString),
+		lipgloss.WithWhitespaceBackground(responseBodyStyle.GetBackground())) + "\n"
+}
+
+// truncateString cuts s down to width characters, so long URLs don't wrap onto the next row of a list.
+func truncateString(s string, width int) string {
+	runes := []rune(s)
+	if width <= 0 || len(runes) <= width {
+		return s
+	}
+	return string(runes[:max(0, width-1)]) + "…"
+}
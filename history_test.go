@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewHistoryEntryCutsOffBodies(t *testing.T) {
+	big := bytes.Repeat([]byte{0xff}, maxHistoryBodyBytes+10)
+	query := QueryData{name: "upload", requestMethod: POST, url: "http://localhost/form", body: big}
+	entry := newHistoryEntry(query, &ResponseData{status: "200 OK", rawBody: big}, nil)
+	if !entry.RequestTruncated || len(entry.requestBody()) != maxHistoryBodyBytes {
+		t.Errorf("request body is %d bytes (cut off %t), want %d", len(entry.requestBody()), entry.RequestTruncated, maxHistoryBodyBytes)
+	}
+	if !entry.BodyTruncated || len(entry.responseBody()) != maxHistoryBodyBytes {
+		t.Errorf("response body is %d bytes (cut off %t), want %d", len(entry.responseBody()), entry.BodyTruncated, maxHistoryBodyBytes)
+	}
+
+	small := newHistoryEntry(QueryData{name: "small", requestMethod: POST, url: "http://localhost/form", body: []byte("a=1")}, nil, os.ErrDeadlineExceeded)
+	if small.RequestTruncated || string(small.requestBody()) != "a=1" {
+		t.Errorf("small body = %q (cut off %t), want it as is", small.requestBody(), small.RequestTruncated)
+	}
+}
+
+func TestLoadHistorySkipsBadLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ws.history.jsonl")
+	// the biggest entry residentsleeper writes still has to load
+	big := newHistoryEntry(QueryData{name: "big", requestMethod: POST, url: "http://localhost/", body: bytes.Repeat([]byte{0}, 2*maxHistoryBodyBytes)},
+		&ResponseData{status: "200 OK", rawBody: bytes.Repeat([]byte("\x01"), 2*maxHistoryBodyBytes)}, nil)
+	for _, entry := range []historyEntry{{QueryName: "first"}, big} {
+		if err := appendHistory(path, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bad := "{\"queryName\": \"cut off\n" + `{"queryName": "` + strings.Repeat("x", maxHistoryLineBytes) + "\"}\n\n"
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(bad)
+	last, _ := json.Marshal(historyEntry{QueryName: "last"})
+	// the last line might not have a newline if writing it was interrupted
+	file.Write(last)
+	file.Close()
+
+	history, skipped, err := loadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, entry := range history {
+		names = append(names, entry.QueryName)
+	}
+	if strings.Join(names, ",") != "first,big,last" || skipped != 2 {
+		t.Errorf("loaded %v and skipped %d, want [first big last] and 2 skipped", names, skipped)
+	}
+}
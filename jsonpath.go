@@ -0,0 +1,242 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type pathStepKind int
+
+const (
+	pathStepChild pathStepKind = iota
+	pathStepIndex
+	pathStepSlice
+	pathStepWildcard
+)
+
+// pathStep is one step of a JSONPath expression, like .name, [2], [1:3] or [*]. Recursive steps (..name) apply to the
+// node and everything below it instead of just the node.
+type pathStep struct {
+	kind      pathStepKind
+	recursive bool
+	name      string
+	index     int
+	// for slices, the bounds that were left out (ex. [:3]) are nil
+	start *int
+	end   *int
+}
+
+// parseJSONPath parses a subset of JSONPath that also accepts jq-style paths, so $.items[0].id, .items[0].id and
+// .items[].id all work. Supported are $ (or a leading .) for the root, .name, ["name"], [n] with negative indexes
+// counting from the end, [start:end], [*] or [] for everything in an object or array and ..name for recursive descent.
+func parseJSONPath(expr string) ([]pathStep, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, errors.New("empty path")
+	}
+	rest := strings.TrimPrefix(expr, "$")
+	// jq's identity path
+	if rest == "." {
+		return []pathStep{}, nil
+	}
+
+	steps := []pathStep{}
+	for rest != "" {
+		recursive := false
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			recursive = true
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+		case strings.HasPrefix(rest, "["):
+		default:
+			return nil, fmt.Errorf("expected . or [ at %q", rest)
+		}
+
+		var step pathStep
+		var err error
+		if strings.HasPrefix(rest, "[") {
+			step, rest, err = parseBracketStep(rest)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			rest = rest[end:]
+			switch name {
+			case "":
+				return nil, fmt.Errorf("missing name in %q", expr)
+			case "*":
+				step = pathStep{kind: pathStepWildcard}
+			default:
+				step = pathStep{kind: pathStepChild, name: name}
+			}
+		}
+		step.recursive = recursive
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// parseBracketStep parses the [...] at the start of s, returning what's left after it.
+func parseBracketStep(s string) (pathStep, string, error) {
+	// quoted names can have ] in them, so they're found before looking for the closing bracket
+	if len(s) > 1 && (s[1] == '"' || s[1] == '\'') {
+		quote := s[1]
+		end := strings.IndexByte(s[2:], quote)
+		if end < 0 || !strings.HasPrefix(s[2+end+1:], "]") {
+			return pathStep{}, "", fmt.Errorf("unterminated name in %q", s)
+		}
+		return pathStep{kind: pathStepChild, name: s[2 : 2+end]}, s[2+end+2:], nil
+	}
+
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return pathStep{}, "", fmt.Errorf("missing ] in %q", s)
+	}
+	inside, rest := strings.TrimSpace(s[1:end]), s[end+1:]
+	if inside == "" || inside == "*" {
+		return pathStep{kind: pathStepWildcard}, rest, nil
+	}
+	if before, after, ok := strings.Cut(inside, ":"); ok {
+		start, err := parseOptionalIndex(before)
+		if err != nil {
+			return pathStep{}, "", err
+		}
+		end, err := parseOptionalIndex(after)
+		if err != nil {
+			return pathStep{}, "", err
+		}
+		return pathStep{kind: pathStepSlice, start: start, end: end}, rest, nil
+	}
+	index, err := strconv.Atoi(inside)
+	if err != nil {
+		return pathStep{}, "", fmt.Errorf("%q isn't an index, a slice or a quoted name", inside)
+	}
+	return pathStep{kind: pathStepIndex, index: index}, rest, nil
+}
+
+func parseOptionalIndex(s string) (*int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, fmt.Errorf("%q isn't an index", s)
+	}
+	return &i, nil
+}
+
+// evaluateJSONPath returns every node in root matching expr, in document order.
+func evaluateJSONPath(root *jsonNode, expr string) ([]*jsonNode, error) {
+	steps, err := parseJSONPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	nodes := []*jsonNode{root}
+	for _, step := range steps {
+		nodes = applyPathStep(nodes, step)
+	}
+	return nodes, nil
+}
+
+func applyPathStep(nodes []*jsonNode, step pathStep) []*jsonNode {
+	if step.recursive {
+		all := []*jsonNode{}
+		for _, node := range nodes {
+			all = appendDescendants(all, node)
+		}
+		nodes = all
+	}
+
+	matches := []*jsonNode{}
+	for _, node := range nodes {
+		switch step.kind {
+		case pathStepChild:
+			if node.kind == jsonObject {
+				for _, child := range node.children {
+					if child.key == step.name {
+						matches = append(matches, child)
+					}
+				}
+			}
+		case pathStepIndex:
+			i := step.index
+			if i < 0 {
+				i += len(node.children)
+			}
+			if node.kind == jsonArray && i >= 0 && i < len(node.children) {
+				matches = append(matches, node.children[i])
+			}
+		case pathStepSlice:
+			if node.kind == jsonArray {
+				start, end := sliceBounds(step, len(node.children))
+				if start < end {
+					matches = append(matches, node.children[start:end]...)
+				}
+			}
+		case pathStepWildcard:
+			matches = append(matches, node.children...)
+		}
+	}
+	return matches
+}
+
+// appendDescendants appends node and everything below it to nodes, parents before their children.
+func appendDescendants(nodes []*jsonNode, node *jsonNode) []*jsonNode {
+	nodes = append(nodes, node)
+	for _, child := range node.children {
+		nodes = appendDescendants(nodes, child)
+	}
+	return nodes
+}
+
+// sliceBounds works like Python slices: negative bounds count from the end and anything out of range is clamped.
+func sliceBounds(step pathStep, length int) (int, int) {
+	bound := func(b *int, fallback int) int {
+		if b == nil {
+			return fallback
+		}
+		i := *b
+		if i < 0 {
+			i += length
+		}
+		return max(0, min(i, length))
+	}
+	return bound(step.start, 0), bound(step.end, length)
+}
+
+// filteredJSONTree is what the tree view shows for a filter: the match itself when there's one, or an array of the
+// matches when there's more. The matches are copied so they can be shown without their keys, but they keep their
+// paths, so folds made while filtering are still there once the filter is cleared.
+func filteredJSONTree(root *jsonNode, expr string) (*jsonNode, error) {
+	matches, err := evaluateJSONPath(root, expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	if len(matches) == 1 {
+		match := *matches[0]
+		match.hasKey = false
+		match.parent = nil
+		return &match, nil
+	}
+	results := &jsonNode{kind: jsonArray, path: "(filter)"}
+	for _, m := range matches {
+		match := *m
+		match.hasKey = false
+		match.parent = results
+		results.children = append(results.children, &match)
+	}
+	return results, nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+	tests := []struct {
+		expr    string
+		want    []pathStep
+		wantErr bool
+	}{
+		{expr: "$", want: []pathStep{}},
+		{expr: ".", want: []pathStep{}},
+		{expr: "$.", want: []pathStep{}},
+		{expr: "$.items[0].id", want: []pathStep{
+			{kind: pathStepChild, name: "items"}, {kind: pathStepIndex, index: 0}, {kind: pathStepChild, name: "id"},
+		}},
+		{expr: ".items[].id", want: []pathStep{
+			{kind: pathStepChild, name: "items"}, {kind: pathStepWildcard}, {kind: pathStepChild, name: "id"},
+		}},
+		{expr: "$.items[*]", want: []pathStep{{kind: pathStepChild, name: "items"}, {kind: pathStepWildcard}}},
+		{expr: "$.*", want: []pathStep{{kind: pathStepWildcard}}},
+		{expr: "$[-1]", want: []pathStep{{kind: pathStepIndex, index: -1}}},
+		{expr: "$[1:3]", want: []pathStep{{kind: pathStepSlice, start: intPtr(1), end: intPtr(3)}}},
+		{expr: "$[:-1]", want: []pathStep{{kind: pathStepSlice, end: intPtr(-1)}}},
+		{expr: "$[2:]", want: []pathStep{{kind: pathStepSlice, start: intPtr(2)}}},
+		{expr: `$["a.b"]['c]']`, want: []pathStep{{kind: pathStepChild, name: "a.b"}, {kind: pathStepChild, name: "c]"}}},
+		{expr: "$..id", want: []pathStep{{kind: pathStepChild, name: "id", recursive: true}}},
+		{expr: "$..[0]", want: []pathStep{{kind: pathStepIndex, index: 0, recursive: true}}},
+		{expr: "  $.id  ", want: []pathStep{{kind: pathStepChild, name: "id"}}},
+		{expr: "", wantErr: true},
+		{expr: "items", wantErr: true},
+		{expr: "$.items.", wantErr: true},
+		{expr: "$.items[0", wantErr: true},
+		{expr: "$.items[a]", wantErr: true},
+		{expr: "$.items[1:b]", wantErr: true},
+		{expr: `$["name]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := parseJSONPath(tt.expr)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseJSONPath(%q) = %+v, want an error", tt.expr, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseJSONPath(%q) returned error: %s", tt.expr, err)
+			}
+			if !slices.EqualFunc(got, tt.want, pathStepsEqual) {
+				t.Errorf("parseJSONPath(%q) = %+v, want %+v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func pathStepsEqual(a, b pathStep) bool {
+	boundsEqual := func(x, y *int) bool {
+		return (x == nil && y == nil) || (x != nil && y != nil && *x == *y)
+	}
+	return a.kind == b.kind && a.recursive == b.recursive && a.name == b.name && a.index == b.index &&
+		boundsEqual(a.start, b.start) && boundsEqual(a.end, b.end)
+}
+
+func TestEvaluateJSONPath(t *testing.T) {
+	root, err := parseJSONTree([]byte(`{
+		"id": 1,
+		"items": [
+			{"id": 10, "tags": ["a", "b"]},
+			{"id": 11, "tags": []},
+			{"id": 12, "owner": {"id": 100}}
+		],
+		"meta": {"count": 3, "next": null}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr    string
+		want    []string
+		wantErr bool
+	}{
+		{expr: "$", want: []string{"$"}},
+		{expr: ".", want: []string{"$"}},
+		{expr: "$.id", want: []string{"$.id"}},
+		{expr: ".meta.count", want: []string{"$.meta.count"}},
+		{expr: `$["meta"]['next']`, want: []string{"$.meta.next"}},
+		{expr: "$.items[0].id", want: []string{"$.items[0].id"}},
+		{expr: "$.items[-1].id", want: []string{"$.items[2].id"}},
+		{expr: "$.items[-3]", want: []string{"$.items[0]"}},
+		{expr: "$.items[1:]", want: []string{"$.items[1]", "$.items[2]"}},
+		{expr: "$.items[:-1].id", want: []string{"$.items[0].id", "$.items[1].id"}},
+		{expr: "$.items[-2:10]", want: []string{"$.items[1]", "$.items[2]"}},
+		{expr: "$.items[*].id", want: []string{"$.items[0].id", "$.items[1].id", "$.items[2].id"}},
+		{expr: ".items[].id", want: []string{"$.items[0].id", "$.items[1].id", "$.items[2].id"}},
+		{expr: ".items[].tags[]", want: []string{"$.items[0].tags[0]", "$.items[0].tags[1]"}},
+		{expr: "$.meta.*", want: []string{"$.meta.count", "$.meta.next"}},
+		{expr: "$..id", want: []string{"$.id", "$.items[0].id", "$.items[1].id", "$.items[2].id", "$.items[2].owner.id"}},
+		{expr: "$.items..id", want: []string{"$.items[0].id", "$.items[1].id", "$.items[2].id", "$.items[2].owner.id"}},
+		{expr: "$..tags[1]", want: []string{"$.items[0].tags[1]"}},
+		{expr: "$.missing", want: []string{}},
+		{expr: "$.items[3]", want: []string{}},
+		{expr: "$.items[-4]", want: []string{}},
+		{expr: "$.items[2:1]", want: []string{}},
+		{expr: "$.id[0]", want: []string{}},
+		{expr: "$.meta[0]", want: []string{}},
+		{expr: "$.items.id", want: []string{}},
+		{expr: "$..nothing", want: []string{}},
+		{expr: "$.items[", wantErr: true},
+		{expr: "items", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			matches, err := evaluateJSONPath(root, tt.expr)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("evaluateJSONPath(%q) succeeded, want an error", tt.expr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("evaluateJSONPath(%q) returned error: %s", tt.expr, err)
+			}
+			got := []string{}
+			for _, match := range matches {
+				got = append(got, match.path)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("evaluateJSONPath(%q) matched %q, want %q", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"strconv"
+	"strings"
+
+	lipgloss "github.com/charmbracelet/lipgloss"
+)
+
+var jsonKeyStyle = responseBodyStyle.Foreground(lipgloss.Color("#8ecae6"))
+var jsonStringStyle = responseBodyStyle.Foreground(lipgloss.Color("#95d5b2"))
+var jsonNumberStyle = responseBodyStyle.Foreground(lipgloss.Color("#ffd166"))
+var jsonLiteralStyle = responseBodyStyle.Foreground(lipgloss.Color("#f4a261"))
+var jsonFoldedStyle = responseBodyStyle.Foreground(lipgloss.Color("#8d99ae"))
+
+type jsonKind int
+
+const (
+	jsonObject jsonKind = iota
+	jsonArray
+	jsonString
+	jsonNumber
+	jsonBool
+	jsonNull
+)
+
+// jsonNode is a parsed JSON value. It's used instead of unmarshalling into map[string]any so objects keep their keys
+// in the order the server sent them.
+type jsonNode struct {
+	kind jsonKind
+	// key is the node's name in its parent object; hasKey is false for array elements and the root
+	key    string
+	hasKey bool
+	// value holds scalars as decoded: string, json.Number, bool or nil
+	value    any
+	children []*jsonNode
+	parent   *jsonNode
+	// path is where the node is in the document (ex. $.users[0].name), which stays the same when the document is
+	// re-rendered, so it's what fold state is keyed on
+	path string
+}
+
+func parseJSONTree(body []byte) (*jsonNode, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	root, err := parseJSONValue(decoder, "$")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the top-level value")
+	}
+	return root, nil
+}
+
+func parseJSONValue(decoder *json.Decoder, path string) (*jsonNode, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	node := &jsonNode{path: path}
+	switch token := token.(type) {
+	case json.Delim:
+		if token == '{' {
+			node.kind = jsonObject
+			for decoder.More() {
+				keyToken, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				key := keyToken.(string)
+				child, err := parseJSONValue(decoder, objectMemberPath(path, key))
+				if err != nil {
+					return nil, err
+				}
+				child.key = key
+				child.hasKey = true
+				child.parent = node
+				node.children = append(node.children, child)
+			}
+		} else {
+			node.kind = jsonArray
+			for i := 0; decoder.More(); i++ {
+				child, err := parseJSONValue(decoder, fmt.Sprintf("%s[%d]", path, i))
+				if err != nil {
+					return nil, err
+				}
+				child.parent = node
+				node.children = append(node.children, child)
+			}
+		}
+		// the closing } or ]
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+	case string:
+		node.kind = jsonString
+		node.value = token
+	case json.Number:
+		node.kind = jsonNumber
+		node.value = token
+	case bool:
+		node.kind = jsonBool
+		node.value = token
+	case nil:
+		node.kind = jsonNull
+	}
+	return node, nil
+}
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func objectMemberPath(path, key string) string {
+	if identifierPattern.MatchString(key) {
+		return path + "." + key
+	}
+	return fmt.Sprintf("%s[%s]", path, strconv.Quote(key))
+}
+
+// jsonLine is one rendered line of a JSON tree. node is the value the line belongs to, which for the line closing an
+// object or array is that object or array.
+type jsonLine struct {
+	node   *jsonNode
+	plain  string
+	styled string
+}
+
+// renderJSONTree renders root as indented, syntax highlighted lines. Nodes whose path is in folded are collapsed onto
+// one line; markers adds a fold marker in front of every line for the tree view.
+func renderJSONTree(root *jsonNode, folded map[string]bool, markers bool) []jsonLine {
+	lines := []jsonLine{}
+	renderJSONNode(root, 0, true, folded, markers, &lines)
+	return lines
+}
+
+func renderJSONNode(node *jsonNode, depth int, last bool, folded map[string]bool, markers bool, lines *[]jsonLine) {
+	indent := strings.Repeat("  ", depth)
+	comma := ","
+	if last {
+		comma = ""
+	}
+	prefix, styledPrefix := indent, responseBodyStyle.Render(indent)
+	if node.hasKey {
+		key := quoteJSONString(node.key)
+		prefix += key + ": "
+		styledPrefix += jsonKeyStyle.Render(key) + responseBodyStyle.Render(": ")
+	}
+	marker := func(s string) string {
+		if markers {
+			return s
+		}
+		return ""
+	}
+
+	if node.kind != jsonObject && node.kind != jsonArray {
+		value, style := jsonScalar(node)
+		*lines = append(*lines, jsonLine{
+			node:   node,
+			plain:  marker("  ") + prefix + value + comma,
+			styled: responseBodyStyle.Render(marker("  ")) + styledPrefix + style.Render(value) + responseBodyStyle.Render(comma),
+		})
+		return
+	}
+
+	open, close, unit := "{", "}", "key"
+	if node.kind == jsonArray {
+		open, close, unit = "[", "]", "item"
+	}
+	if len(node.children) == 0 {
+		*lines = append(*lines, jsonLine{
+			node:   node,
+			plain:  marker("  ") + prefix + open + close + comma,
+			styled: responseBodyStyle.Render(marker("  ")) + styledPrefix + responseBodyStyle.Render(open+close+comma),
+		})
+		return
+	}
+	if folded[node.path] {
+		summary := fmt.Sprintf("%s…%s", open, close)
+		count := fmt.Sprintf(" %d %s", len(node.children), unit)
+		if len(node.children) != 1 {
+			count += "s"
+		}
+		*lines = append(*lines, jsonLine{
+			node:   node,
+			plain:  marker("▸ ") + prefix + summary + comma + count,
+			styled: responseBodyStyle.Render(marker("▸ ")) + styledPrefix + responseBodyStyle.Render(summary+comma) + jsonFoldedStyle.Render(count),
+		})
+		return
+	}
+
+	*lines = append(*lines, jsonLine{
+		node:   node,
+		plain:  marker("▾ ") + prefix + open,
+		styled: responseBodyStyle.Render(marker("▾ ")) + styledPrefix + responseBodyStyle.Render(open),
+	})
+	for i, child := range node.children {
+		renderJSONNode(child, depth+1, i == len(node.children)-1, folded, markers, lines)
+	}
+	*lines = append(*lines, jsonLine{
+		node:   node,
+		plain:  marker("  ") + indent + close + comma,
+		styled: responseBodyStyle.Render(marker("  ") + indent + close + comma),
+	})
+}
+
+func jsonScalar(node *jsonNode) (string, lipgloss.Style) {
+	switch node.kind {
+	case jsonString:
+		return quoteJSONString(node.value.(string)), jsonStringStyle
+	case jsonNumber:
+		return node.value.(json.Number).String(), jsonNumberStyle
+	case jsonBool:
+		return strconv.FormatBool(node.value.(bool)), jsonLiteralStyle
+	}
+	return "null", jsonLiteralStyle
+}
+
+// quoteJSONString encodes s as a JSON string without escaping HTML characters like json.Marshal does.
+func quoteJSONString(s string) string {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(buffer.String(), "\n")
+}
+
+// setAllFolded folds or unfolds every object and array below root. The root itself is never folded, since a single
+// folded line isn't much to look at.
+func setAllFolded(root *jsonNode, folded map[string]bool, fold bool) {
+	for _, child := range root.children {
+		if child.kind == jsonObject || child.kind == jsonArray {
+			if fold {
+				folded[child.path] = true
+			} else {
+				delete(folded, child.path)
+			}
+			setAllFolded(child, folded, fold)
+		}
+	}
+}
+
+func isFoldable(node *jsonNode) bool {
+	return (node.kind == jsonObject || node.kind == jsonArray) && len(node.children) > 0
+}
+
+// isJSONMediaType is true for application/json, text/json and anything using the +json suffix.
+func isJSONMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// responseJSONTree parses body when it's JSON, or returns nil so the body is shown as formatted text.
+func responseJSONTree(contentType string, body []byte) *jsonNode {
+	if !isJSONMediaType(contentType) {
+		return nil
+	}
+	root, err := parseJSONTree(body)
+	if err != nil {
+		return nil
+	}
+	return root
+}
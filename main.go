@@ -1,16 +1,18 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"flag"
 	"fmt"
-	"io"
+	"maps"
 	"net/http"
 	"os"
 	"slices"
 	"strings"
 	"time"
+	"unicode"
 
+	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textarea"
@@ -27,7 +29,34 @@ type keyMap struct {
 	ListNext           key.Binding
 	ListAdd            key.Binding
 	ListDelete         key.Binding
+	DuplicateQuery     key.Binding
+	RenameQuery        key.Binding
+	ImportCurl         key.Binding
+	Confirm            key.Binding
 	EditURL            key.Binding
+	CycleMethod        key.Binding
+	EditMethod         key.Binding
+	CycleEnvironment   key.Binding
+	CancelRequest      key.Binding
+	CycleResponseView  key.Binding
+	ToggleTreeView     key.Binding
+	ToggleFold         key.Binding
+	FoldAll            key.Binding
+	UnfoldAll          key.Binding
+	Search             key.Binding
+	NextMatch          key.Binding
+	PrevMatch          key.Binding
+	ToggleSearchRegex  key.Binding
+	FilterResponse     key.Binding
+	ReplayHistory      key.Binding
+	RestoreHistory     key.Binding
+	MarkDiff           key.Binding
+	ExportCurl         key.Binding
+	ExportGo           key.Binding
+	CycleBodyMode      key.Binding
+	FormatJSONBody     key.Binding
+	OpenInEditor       key.Binding
+	OpenInPager        key.Binding
 	Submit             key.Binding
 	OpenQuerySelection key.Binding
 	UnfocusTextInput   key.Binding
@@ -41,12 +70,35 @@ func (k keyMap) ShortHelp() []key.Binding {
 
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.TabRight, k.UnfocusTextInput},
-		{k.ListPrev, k.OpenQuerySelection},
-		{k.Submit, k.Quit},
+		{k.TabRight, k.ListPrev, k.OpenQuerySelection, k.UnfocusTextInput, k.Quit},
+		{k.EditURL, k.CycleMethod, k.EditMethod, k.CycleEnvironment},
+		{k.ListAdd, k.DuplicateQuery, k.RenameQuery, k.ImportCurl, k.MarkDiff},
+		{k.Submit, k.CancelRequest, k.ExportCurl, k.ExportGo},
 	}
 }
 
+// tabHelp adds the keys that only do something in the open tab to the help, since there are too many to show them all
+// at once.
+type tabHelp struct {
+	keyMap
+	tab UITab
+}
+
+func (h tabHelp) FullHelp() [][]key.Binding {
+	columns := h.keyMap.FullHelp()
+	switch h.tab {
+	case TabResponse:
+		columns = append(columns,
+			[]key.Binding{h.CycleResponseView, h.ToggleTreeView, h.ToggleFold, h.FoldAll, h.OpenInPager},
+			[]key.Binding{h.Search, h.NextMatch, h.ToggleSearchRegex, h.FilterResponse})
+	case TabBody:
+		columns = append(columns, []key.Binding{h.CycleBodyMode, h.FormatJSONBody, h.OpenInEditor})
+	case TabHistory:
+		columns = append(columns, []key.Binding{h.ReplayHistory, h.RestoreHistory})
+	}
+	return columns
+}
+
 var keys = keyMap{
 	TabRight: key.NewBinding(
 		key.WithKeys("right"),
@@ -72,10 +124,119 @@ var keys = keyMap{
 		key.WithKeys("x"),
 		key.WithHelp("x", "delete focused"),
 	),
+	DuplicateQuery: key.NewBinding(
+		key.WithKeys("d"),
+		key.WithHelp("d", "duplicate query"),
+	),
+	RenameQuery: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "rename query"),
+	),
+	ImportCurl: key.NewBinding(
+		key.WithKeys("i"),
+		key.WithHelp("i", "import curl"),
+	),
+	Confirm: key.NewBinding(
+		key.WithKeys("y"),
+		key.WithHelp("y", "confirm"),
+	),
 	EditURL: key.NewBinding(
 		key.WithKeys("u"),
 		key.WithHelp("u", "edit url"),
 	),
+	CycleMethod: key.NewBinding(
+		key.WithKeys("m"),
+		key.WithHelp("m", "cycle method"),
+	),
+	EditMethod: key.NewBinding(
+		key.WithKeys("M"),
+		key.WithHelp("M", "custom method"),
+	),
+	CycleEnvironment: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "switch environment"),
+	),
+	CancelRequest: key.NewBinding(
+		key.WithKeys("ctrl+x"),
+		key.WithHelp("ctrl+x", "cancel request"),
+	),
+	CycleResponseView: key.NewBinding(
+		key.WithKeys("v"),
+		key.WithHelp("v", "change response view"),
+	),
+	ToggleTreeView: key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("t", "tree view"),
+	),
+	ToggleFold: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("space", "fold/unfold"),
+	),
+	FoldAll: key.NewBinding(
+		key.WithKeys("-"),
+		key.WithHelp("-/+", "fold/unfold all"),
+	),
+	UnfoldAll: key.NewBinding(
+		key.WithKeys("+"),
+		key.WithHelp("+", "unfold all"),
+	),
+	Search: key.NewBinding(
+		key.WithKeys("/"),
+		key.WithHelp("/", "search response"),
+	),
+	NextMatch: key.NewBinding(
+		key.WithKeys("n"),
+		key.WithHelp("n/N", "next/prev match"),
+	),
+	PrevMatch: key.NewBinding(
+		key.WithKeys("N"),
+		key.WithHelp("N", "prev match"),
+	),
+	ToggleSearchRegex: key.NewBinding(
+		key.WithKeys("ctrl+r"),
+		key.WithHelp("ctrl+r", "toggle regex search"),
+	),
+	FilterResponse: key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "filter json"),
+	),
+	ReplayHistory: key.NewBinding(
+		key.WithKeys("p"),
+		key.WithHelp("p", "replay from history"),
+	),
+	RestoreHistory: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "save history as query"),
+	),
+	MarkDiff: key.NewBinding(
+		key.WithKeys("="),
+		key.WithHelp("=", "diff responses"),
+	),
+	ExportCurl: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "copy as curl"),
+	),
+	ExportGo: key.NewBinding(
+		key.WithKeys("C"),
+		key.WithHelp("C", "copy as Go"),
+	),
+	CycleBodyMode: key.NewBinding(
+		key.WithKeys("b"),
+		key.WithHelp("b", "change body type"),
+	),
+	FormatJSONBody: key.NewBinding(
+		// not ctrl+f, which the textarea uses to move the cursor forward
+		key.WithKeys("ctrl+l"),
+		key.WithHelp("ctrl+l", "format/minify json"),
+	),
+	OpenInEditor: key.NewBinding(
+		key.WithKeys("E"),
+		key.WithHelp("E", "edit in $EDITOR"),
+	),
+	OpenInPager: key.NewBinding(
+		key.WithKeys("P"),
+		key.WithHelp("P", "open in $PAGER"),
+	),
 	Submit: key.NewBinding(
 		key.WithKeys("enter"),
 		key.WithHelp("enter", "submit"),
@@ -107,28 +268,59 @@ var responseClientErrorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#2
 var responseServerErrorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#222222")).Background(lipgloss.Color("#cb0b0a"))
 
 const querySelectionTabWidth = 30
+const textInputWidth = 60
+const textInputCharLimit = 150
 
 type HTTPMethod string
 
 const (
-	POST HTTPMethod = "POST"
-	GET  HTTPMethod = "GET"
+	GET     HTTPMethod = "GET"
+	POST    HTTPMethod = "POST"
+	PUT     HTTPMethod = "PUT"
+	PATCH   HTTPMethod = "PATCH"
+	DELETE  HTTPMethod = "DELETE"
+	HEAD    HTTPMethod = "HEAD"
+	OPTIONS HTTPMethod = "OPTIONS"
 )
 
+// httpMethods is the order the cycle method key goes through; anything else has to be typed in as a custom method.
+var httpMethods = []HTTPMethod{GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS}
+
 type UIState string
 
 const (
 	UIStateWaitingForInput     UIState = "Waiting for user input"
 	UIStateSelectingQuery      UIState = "Selecting query to use"
+	UIStateRenamingQuery       UIState = "Renaming query"
+	UIStateDeletingQuery       UIState = "Delete this query? (y/n)"
+	UIStateConfirmingSend      UIState = "Body isn't valid JSON, send anyway? (y/n)"
+	UIStateImportingCurl       UIState = "Importing curl command"
 	UIStateEditingURL          UIState = "Editing URL to send request to"
+	UIStateEditingMethod       UIState = "Editing HTTP method"
 	UIStateAddingQueryParam    UIState = "Adding request query parameter"
 	UIStateEditingQueryParam   UIState = "Editing request query parameter"
 	UIStateEditingHeader       UIState = "Editing request header"
 	UIStateAddingHeader        UIState = "Adding request header"
 	UIStateEditingBody         UIState = "Editing request body"
+	UIStateAddingAssertion     UIState = "Adding assertion"
+	UIStateEditingAssertion    UIState = "Editing assertion"
+	UIStateAddingExtraction    UIState = "Adding extraction"
+	UIStateEditingExtraction   UIState = "Editing extraction"
+	UIStateAddingFormField     UIState = "Adding form field"
+	UIStateEditingFormField    UIState = "Editing form field"
+	UIStateEditingScript       UIState = "Editing script"
 	UIStateWaitingForResponse  UIState = "Sent HTTP request, waiting for HTTP response"
 	UIStateShowingResponse     UIState = "Received HTTP response"
 	UIStateShowingRequestError UIState = "Received error sending HTTP request"
+	UIStateRequestCancelled    UIState = "Cancelled HTTP request"
+	UIStateEditingSetting      UIState = "Editing setting"
+	UIStateEditingCookie       UIState = "Editing cookie"
+	UIStateEditingAuth         UIState = "Editing auth"
+	UIStateShowingExport       UIState = "Showing exported request"
+	UIStateSearchingResponse   UIState = "Searching response"
+	UIStateShowingHistoryEntry UIState = "Showing request from history"
+	UIStateShowingDiff         UIState = "Showing diff of two responses"
+	UIStateFilteringResponse   UIState = "Filtering response (ex. $.items[0].id or .items[].id)"
 	UIStateUserQuit            UIState = "Exiting program..."
 )
 
@@ -136,17 +328,37 @@ type UITab string
 
 const (
 	TabQueryParams UITab = "Params"
+	TabAuth        UITab = "Auth"
 	TabHeaders     UITab = "Headers"
 	TabBody        UITab = "Body"
+	TabTests       UITab = "Tests"
+	TabExtract     UITab = "Extract"
+	TabScripts     UITab = "Scripts"
+	TabSettings    UITab = "Settings"
 	TabResponse    UITab = "Response"
+	TabHistory     UITab = "History"
+	TabCookies     UITab = "Cookies"
 )
 
 type ResponseData struct {
-	status      string
-	header      http.Header
-	body        string
-	timeElapsed string
-	err         error
+	status  string
+	header  http.Header
+	cookies []*http.Cookie
+	rawBody []byte
+	// body is rawBody formatted for its content type, or rawBody as is when formatErr says why it couldn't be
+	body      string
+	formatErr error
+	// jsonTree is the parsed body for JSON responses, with folded holding the paths folded in tree mode
+	jsonTree *jsonNode
+	folded   map[string]bool
+	// assertionResults are from checking the query's assertions against this response
+	assertionResults []assertionResult
+	timeElapsed      string
+	timing           responseTiming
+	err              error
+	// redirectChain is every request it took to get this response, ending with the one that got it
+	redirectChain   []redirectHop
+	redirectStopped bool
 }
 
 type HeaderData struct {
@@ -160,46 +372,124 @@ type QueryParamData struct {
 }
 
 type QueryData struct {
-	name          string
-	url           string
-	body          []byte
+	name string
+	url  string
+	body []byte
+	// bodyMode is how the body's sent, with form and multipart bodies built from formFields, see encodeBody
+	bodyMode      bodyMode
+	formFields    []QueryParamData
 	headers       []HeaderData
 	queryParams   []QueryParamData
 	requestMethod HTTPMethod
 	responseData  *ResponseData
+	// auth is added to the request when it's sent, see applyAuth
+	auth queryAuth
+	// assertions are checked against every response, see parseAssertion for what they look like
+	assertions []string
+	// extractions set variables from every response, see parseExtraction
+	extractions []string
+	// preScript runs before the query's sent and postScript after its response comes back, see runScript
+	preScript  string
+	postScript string
+	// timeout and simulatedLatency override the workspace's defaults when they aren't 0
+	timeout                time.Duration
+	simulatedLatency       time.Duration
+	simulatedLatencyJitter time.Duration
+	// maxRedirects is how many redirects to follow, with 0 being the default and -1 not following them
+	maxRedirects int
+	// skipCookieJar sends the query without the workspace's cookies and doesn't keep the ones it gets back
+	skipCookieJar bool
 }
 
 type model struct {
-	queries          []QueryData
-	currentQueryData *QueryData
-	uiState          UIState
-	tabs             []UITab
-	currentTab       UITab
-	viewport         viewport.Model
-	textarea         textarea.Model
-	help             help.Model
-	keys             keyMap
-	textInput        textinput.Model
-	focusedHeader    int
-	focusedParam     int
-	focusedQuery     int
-	screenWidth      int
-	mainTabWidth     int
-	bodyHeight       int
+	queries            []QueryData
+	currentQueryData   *QueryData
+	uiState            UIState
+	tabs               []UITab
+	currentTab         UITab
+	viewport           viewport.Model
+	textarea           textarea.Model
+	scriptArea         textarea.Model
+	help               help.Model
+	keys               keyMap
+	textInput          textinput.Model
+	focusedHeader      int
+	focusedParam       int
+	focusedAssertion   int
+	focusedExtraction  int
+	focusedFormField   int
+	focusedQuery       int
+	screenWidth        int
+	mainTabWidth       int
+	bodyHeight         int
+	workspacePath      string
+	statusMessage      string
+	environments       []Environment
+	currentEnvironment int
+	settings           workspaceSettings
+	focusedSetting     int
+	focusedAuthField   int
+	cancelRequest      context.CancelFunc
+	responseViews      []ResponseView
+	currentView        ResponseView
+	// treeMode shows JSON bodies as a tree that can be folded, with treeCursor being the focused line of treeLines
+	treeMode   bool
+	treeCursor int
+	treeLines  []jsonLine
+	// viewportLines is what's in the viewport without any styling, which is what searches look through
+	viewportLines []string
+	searchQuery   string
+	searchRegex   bool
+	searchMatches []searchMatch
+	searchErr     error
+	currentMatch  int
+	// responseFilter is a JSONPath expression that narrows down what JSON bodies show
+	responseFilter string
+	// runtimeVariables are the values extracted from responses, which requests use over the environment's
+	runtimeVariables map[string]string
+	// showingPostScript is whether the Scripts tab is on the post-response script rather than the pre-request one
+	showingPostScript bool
+	// console is everything scripts have printed, oldest first
+	console []consoleLine
+	// oauthTokens are the OAuth2 tokens fetched so far, by oauthCacheKey
+	oauthTokens map[string]oauthToken
+	// cookieJar is shared by every query in the workspace, with focusedCookie being the focused one in the Cookies tab
+	cookieJar     *cookieJar
+	focusedCookie int
+	// history is every request sent from this workspace, oldest first
+	history        []historyEntry
+	focusedHistory int
+	// sentQuery is the resolved query that's waiting on a response, kept for the history. sentQueryIndex is where the
+	// query it was resolved from is in queries, which gets the response even if another query's picked in the meantime,
+	// or -1 if that query's been deleted since.
+	sentQuery      QueryData
+	sentQueryIndex int
+	// requestID counts the requests sent, so only the latest one's response is shown. inFlight is whether that request
+	// hasn't come back or been cancelled yet, counting its pre-request script and OAuth2 token, since only one's sent at a time.
+	requestID int
+	inFlight  bool
+	// replaying is whether sentQuery is a history entry being replayed, whose response goes in replayResponse rather
+	// than to the current query. It's shown instead of the current query's response until another query is sent or picked.
+	replaying      bool
+	replayResponse *ResponseData
+	// diffBase is the response marked to diff against, until the second one is picked and they're put in diffSides
+	diffBase       *diffSide
+	diffSides      [2]diffSide
+	diffStructural bool
 }
 
 func (m model) Init() tea.Cmd {
 	return tea.SetWindowTitle("residentsleeper")
 }
 
-func initialModel() model {
+func initialModel(workspacePath string, ws workspaceFile, history []historyEntry, jar *cookieJar) model {
 	modelHelp := help.New()
 	modelHelp.ShowAll = true
 
 	ti := textinput.New()
 	ti.Placeholder = "Enter header (ex. Accept:application/json;v=2)"
-	ti.CharLimit = 150
-	ti.Width = 60
+	ti.CharLimit = textInputCharLimit
+	ti.Width = textInputWidth
 	ti.TextStyle = tabOpenStyle
 	ti.PlaceholderStyle = tabClosedStyle
 	ti.Cursor.Style = tabOpenStyle
@@ -208,20 +498,44 @@ func initialModel() model {
 	viewport.YPosition = 3
 	viewport.Style = responseBodyStyle
 
-	helloQuery := QueryData{
-		name: "mock server hello",
-		url:  "http://localhost:8090/hello",
-		body: []byte(" "),
-		headers: []HeaderData{
-			{name: "Accept", value: "application/json;v=2"},
-			{name: "Content-Type", value: "application/json"},
-			{name: "User-Agent", value: "dylanpruitt-go-client"},
-		},
-		queryParams:   []QueryParamData{},
-		requestMethod: GET,
-		responseData:  nil,
+	queries := ws.queryData()
+	if len(queries) == 0 {
+		queries = append(queries, QueryData{name: "new query", requestMethod: GET, headers: []HeaderData{}, queryParams: []QueryParamData{}})
 	}
 
+	ta := newTextarea("Enter request body here")
+	ta.SetValue(string(queries[0].body))
+	scriptArea := newTextarea("Enter a Starlark script here (ex. request.headers[\"X-Timestamp\"] = str(time.now().unix))")
+	scriptArea.SetValue(queries[0].preScript)
+
+	return model{
+		queries:            queries,
+		currentQueryData:   &queries[0],
+		uiState:            UIStateSelectingQuery,
+		tabs:               []UITab{TabQueryParams, TabAuth, TabHeaders, TabBody, TabTests, TabExtract, TabScripts, TabSettings, TabResponse, TabHistory, TabCookies},
+		currentTab:         TabHeaders,
+		help:               modelHelp,
+		keys:               keys,
+		textarea:           ta,
+		scriptArea:         scriptArea,
+		viewport:           viewport,
+		textInput:          ti,
+		focusedHeader:      0,
+		focusedQuery:       0,
+		workspacePath:      workspacePath,
+		environments:       ws.environments(),
+		currentEnvironment: environmentIndex(ws.environments(), ws.ActiveEnvironment),
+		runtimeVariables:   map[string]string{},
+		oauthTokens:        map[string]oauthToken{},
+		settings:           ws.settings(),
+		responseViews:      []ResponseView{ResponseViewBody, ResponseViewHeaders, ResponseViewCookies, ResponseViewTiming, ResponseViewRedirects, ResponseViewTests},
+		currentView:        ResponseViewBody,
+		history:            history,
+		cookieJar:          jar,
+	}
+}
+
+func newTextarea(placeholder string) textarea.Model {
 	ta := textarea.New()
 	ta.SetWidth(120)
 	ta.SetHeight(20)
@@ -229,119 +543,21 @@ func initialModel() model {
 	ta.BlurredStyle.Base = responseBodyStyle
 	ta.FocusedStyle.Base = responseBodyStyle
 	ta.FocusedStyle.CursorLine = tabOpenStyle
-	ta.Placeholder = "Enter request body here"
-	ta.SetValue(string(helloQuery.body))
+	ta.Placeholder = placeholder
+	return ta
+}
 
-	return model{
-		queries: []QueryData{
-			helloQuery,
-			{
-				name: "mock server headers",
-				url:  "http://localhost:8090/headers",
-				body: []byte(" "),
-				headers: []HeaderData{
-					{name: "Accept", value: "*/*"},
-					{name: "Content-Type", value: "application/json"},
-					{name: "User-Agent", value: "dylanpruitt-go-client"},
-				},
-				queryParams:   []QueryParamData{},
-				requestMethod: GET,
-				responseData:  nil,
-			},
-			{
-				name: "mock api call v1",
-				url:  "http://localhost:8090/user/1",
-				body: []byte(" "),
-				headers: []HeaderData{
-					{name: "Accept", value: "application/json;v=1"},
-					{name: "Content-Type", value: "application/json"},
-					{name: "User-Agent", value: "dylanpruitt-go-client"},
-				},
-				queryParams:   []QueryParamData{},
-				requestMethod: GET,
-				responseData:  nil,
-			},
-			{
-				name: "mock api call v2",
-				url:  "http://localhost:8090/user/1",
-				body: []byte(" "),
-				headers: []HeaderData{
-					{name: "Accept", value: "application/json;v=2"},
-					{name: "Content-Type", value: "application/json"},
-					{name: "User-Agent", value: "dylanpruitt-go-client"},
-				},
-				queryParams:   []QueryParamData{},
-				requestMethod: GET,
-				responseData:  nil,
-			},
-			{
-				name: "mock api call 404",
-				url:  "http://localhost:8090/user/doesntexistlmao",
-				body: []byte(" "),
-				headers: []HeaderData{
-					{name: "Accept", value: "application/json;v=2"},
-					{name: "Content-Type", value: "application/json"},
-					{name: "User-Agent", value: "dylanpruitt-go-client"},
-				},
-				queryParams:   []QueryParamData{},
-				requestMethod: GET,
-				responseData:  nil,
-			},
-			{
-				name: "mock api post",
-				url:  "http://localhost:8090/user/2",
-				body: []byte("{\"FirstName\":\"Ben\",\"LastName\":\"L\",\"Money\":0}"),
-				headers: []HeaderData{
-					{name: "Content-Type", value: "application/json"},
-					{name: "User-Agent", value: "dylanpruitt-go-client"},
-				},
-				queryParams:   []QueryParamData{},
-				requestMethod: POST,
-				responseData:  nil,
-			},
-			{
-				name: "mock api get all users",
-				url:  "http://localhost:8090/users",
-				body: []byte(" "),
-				headers: []HeaderData{
-					{name: "Accept", value: "*/*"},
-					{name: "Content-Type", value: "application/json"},
-					{name: "User-Agent", value: "dylanpruitt-go-client"},
-				},
-				queryParams:   []QueryParamData{},
-				requestMethod: GET,
-				responseData:  nil,
-			},
-			{
-				name: "long response",
-				url:  "http://localhost:8090/long-response",
-				body: []byte(" "),
-				headers: []HeaderData{
-					{name: "Accept", value: "*/*"},
-					{name: "User-Agent", value: "dylanpruitt-go-client"},
-				},
-				queryParams:   []QueryParamData{},
-				requestMethod: GET,
-				responseData:  nil,
-			},
-		},
-		currentQueryData: &helloQuery,
-		uiState:          UIStateSelectingQuery,
-		tabs:             []UITab{TabQueryParams, TabHeaders, TabBody, TabResponse},
-		currentTab:       TabHeaders,
-		help:             modelHelp,
-		keys:             keys,
-		textarea:         ta,
-		viewport:         viewport,
-		textInput:        ti,
-		focusedHeader:    0,
-		focusedQuery:     0,
-	}
-}
-
-type responseMsg *ResponseData
-
-type errMsg struct{ err error }
+// responseMsg and errMsg are how a request turns out. requestID is which request it was, so one that's been cancelled
+// or replaced can be told apart from the one being waited on.
+type responseMsg struct {
+	requestID int
+	response  *ResponseData
+}
+
+type errMsg struct {
+	requestID int
+	err       error
+}
 
 func (e errMsg) Error() string { return e.err.Error() }
 
@@ -354,51 +570,313 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 
 	case responseMsg:
-		m.currentQueryData.responseData = msg
-		m.viewport.SetContent(m.currentQueryData.responseData.body)
+		if msg.requestID != m.requestID {
+			return m, nil
+		}
+		m.inFlight = false
+		m.recordHistory(m.sentQuery, msg.response, nil)
+		if m.replaying {
+			// a replay isn't any saved query's response, so none of their tests, extractions or scripts apply to it
+			m.replayResponse = msg.response
+			m.statusMessage = fmt.Sprintf("replayed %s from the history, the response isn't kept with the query", m.sentQuery.name)
+		} else {
+			cmd = m.finishResponse(msg.response)
+		}
+		if err := m.saveCookieJar(); err != nil {
+			m.appendStatus(fmt.Sprintf("couldn't save cookies: %s", err))
+		}
+		if !m.replaying && m.sentQueryIndex != m.focusedQuery {
+			// another query's been picked since, so the response is left with its query rather than shown over this one
+			m.appendStatus(fmt.Sprintf("%s got a response back", m.sentQuery.name))
+			return m, cmd
+		}
+		m.treeCursor = 0
+		m.refreshViewport()
 		m.uiState = UIStateShowingResponse
 		m.currentTab = TabResponse
-		return m, nil
+		return m, cmd
 
 	case errMsg:
-		m.currentQueryData.responseData = &ResponseData{}
-		m.currentQueryData.responseData.err = msg
+		if msg.requestID != m.requestID {
+			return m, nil
+		}
+		m.inFlight = false
+		m.recordHistory(m.sentQuery, nil, msg.err)
+		if m.replaying {
+			m.replayResponse = &ResponseData{err: msg}
+		} else if m.sentQueryIndex >= 0 {
+			m.queries[m.sentQueryIndex].responseData = &ResponseData{err: msg}
+		}
+		if !m.replaying && m.sentQueryIndex != m.focusedQuery {
+			m.statusMessage = fmt.Sprintf("sending %s failed: %s", m.sentQuery.name, msg.err)
+			return m, nil
+		}
 		m.uiState = UIStateShowingRequestError
 		return m, nil
 
+	case preRequestScriptMsg:
+		if msg.requestID != m.requestID {
+			return m, nil
+		}
+		name := msg.query.name + " pre-request"
+		m.logScript(name, msg.output, msg.err)
+		if msg.err != nil {
+			m.inFlight = false
+			m.uiState = UIStateWaitingForInput
+			m.statusMessage = fmt.Sprintf("%s script failed so the request wasn't sent, see the Scripts tab", name)
+			return m, nil
+		}
+		maps.Copy(m.runtimeVariables, msg.set)
+		return m, sendEncodedQuery(&m, msg.query)
+
+	case postResponseScriptMsg:
+		m.logScript(msg.name, msg.output, msg.err)
+		if msg.err != nil {
+			m.appendStatus(fmt.Sprintf("%s script failed, see the Scripts tab", msg.name))
+		} else {
+			maps.Copy(m.runtimeVariables, msg.set)
+		}
+		return m, nil
+
+	case externalProgramMsg:
+		m.finishExternalProgram(msg)
+		return m, nil
+
+	case oauthTokenMsg:
+		m.oauthTokens[msg.cacheKey] = msg.token
+		// the request that needed the token goes out now that it's cached, unless it was cancelled in the meantime
+		if msg.requestID != m.requestID {
+			return m, nil
+		}
+		return m, sendAuthorizedQuery(&m, msg.query, msg.token.accessToken)
+
 	case tea.WindowSizeMsg:
 		m.screenWidth = msg.Width
 		m.mainTabWidth = m.screenWidth - querySelectionTabWidth
 		m.help.Width = m.screenWidth
 		m.bodyHeight = msg.Height - 5
 		m.viewport.Width = m.mainTabWidth
-		m.viewport.Height = m.bodyHeight
+		// one line shorter to leave room for the response view bar
+		m.viewport.Height = m.bodyHeight - 1
 		m.textarea.SetWidth(m.mainTabWidth)
-		m.textarea.SetHeight(m.bodyHeight)
+		// one line less for the body type above it
+		m.textarea.SetHeight(m.bodyHeight - 1)
+		// the Scripts tab also fits a line saying which script it is and the console under the script
+		m.scriptArea.SetWidth(m.mainTabWidth)
+		m.scriptArea.SetHeight(max(1, m.bodyHeight-consoleHeight(m.bodyHeight)-2))
 
 	case tea.KeyMsg:
+		m.statusMessage = ""
+		// the sidebar reuses a few keys the tabs also use (add/delete), so it gets first pick while a query is being selected
+		if m.uiState == UIStateDeletingQuery {
+			if key.Matches(msg, m.keys.Confirm) {
+				m.deleteFocusedQuery()
+				m.persistWorkspace()
+			}
+			m.uiState = UIStateSelectingQuery
+			return m, nil
+		}
+		if m.uiState == UIStateConfirmingSend {
+			if key.Matches(msg, m.keys.Confirm) {
+				m.uiState = UIStateWaitingForResponse
+				return m, sendRequestFromModel(&m)
+			}
+			m.uiState = UIStateWaitingForInput
+			return m, nil
+		}
+		if m.uiState == UIStateSearchingResponse {
+			if key.Matches(msg, m.keys.Submit) {
+				m.textInput.Blur()
+				m.uiState = UIStateShowingResponse
+				if m.searchQuery == "" {
+					m.clearSearch()
+				}
+				return m, nil
+			}
+			if key.Matches(msg, m.keys.UnfocusTextInput) {
+				m.textInput.Blur()
+				m.uiState = UIStateShowingResponse
+				m.clearSearch()
+				return m, nil
+			}
+			if key.Matches(msg, m.keys.ToggleSearchRegex) {
+				m.searchRegex = !m.searchRegex
+			} else {
+				m.textInput, cmd = m.textInput.Update(msg)
+				m.searchQuery = m.textInput.Value()
+			}
+			// searching as you type, so there's no need to press enter to find out if something's there
+			m.updateSearchMatches()
+			m.renderViewport()
+			m.gotoFirstSearchMatch()
+			return m, cmd
+		}
+		if m.uiState == UIStateFilteringResponse {
+			if key.Matches(msg, m.keys.Submit) {
+				filter := strings.TrimSpace(m.textInput.Value())
+				if filter != "" {
+					if _, err := parseJSONPath(filter); err != nil {
+						m.statusMessage = fmt.Sprintf("invalid filter: %s", err)
+						return m, nil
+					}
+				}
+				m.textInput.Blur()
+				m.uiState = UIStateShowingResponse
+				m.responseFilter = filter
+				m.treeCursor = 0
+				m.refreshViewport()
+				m.viewport.GotoTop()
+				return m, nil
+			}
+			if key.Matches(msg, m.keys.UnfocusTextInput) {
+				m.textInput.Blur()
+				m.uiState = UIStateShowingResponse
+				return m, nil
+			}
+		}
+		if m.uiState == UIStateSelectingQuery {
+			if key.Matches(msg, m.keys.ListAdd) {
+				m.insertQuery(QueryData{name: "new query", requestMethod: GET, headers: []HeaderData{}, queryParams: []QueryParamData{}})
+				m.persistWorkspace()
+				m.startRenamingQuery()
+				m.textInput.SetValue("")
+				return m, nil
+			}
+			if key.Matches(msg, m.keys.DuplicateQuery) {
+				m.insertQuery(copyQuery(*m.currentQueryData, m.currentQueryData.name+" copy"))
+				m.persistWorkspace()
+				return m, nil
+			}
+			if key.Matches(msg, m.keys.RenameQuery) {
+				m.startRenamingQuery()
+				return m, nil
+			}
+			if key.Matches(msg, m.keys.MarkDiff) {
+				response := m.currentQueryData.responseData
+				if response == nil || response.status == "" {
+					m.statusMessage = fmt.Sprintf("send %s first to have a response to diff", m.currentQueryData.name)
+					return m, nil
+				}
+				m.markDiffSide(diffSideFromResponse(m.currentQueryData.name, response))
+				return m, nil
+			}
+			if key.Matches(msg, m.keys.ImportCurl) {
+				m.uiState = UIStateImportingCurl
+				m.focusTextInputAndSetValue("")
+				// curl commands are usually longer than anything else typed in here
+				m.textInput.CharLimit = 0
+				m.textInput.Placeholder = "Paste a curl command"
+				return m, nil
+			}
+			if key.Matches(msg, m.keys.ListDelete) {
+				if len(m.queries) == 1 {
+					m.statusMessage = "can't delete the only query"
+					return m, nil
+				}
+				m.uiState = UIStateDeletingQuery
+				m.statusMessage = m.currentQueryData.name
+				return m, nil
+			}
+		}
 		if key.Matches(msg, m.keys.Submit) {
 			if m.uiState == UIStateSelectingQuery && m.currentTab != TabResponse {
 				m.uiState = UIStateWaitingForInput
 				break
 			}
-			if m.currentTab == TabBody {
+			if m.currentTab == TabBody && !usesFormFields(m.currentQueryData.bodyMode) {
 				if m.uiState != UIStateEditingBody {
 					m.textarea.Focus()
 					m.uiState = UIStateEditingBody
 					return m, nil
 				}
 			}
+			if m.currentTab == TabScripts {
+				if m.uiState != UIStateEditingScript {
+					m.scriptArea.Focus()
+					m.uiState = UIStateEditingScript
+					return m, nil
+				}
+			}
 			if m.uiState == UIStateEditingURL {
 				m.currentQueryData.url = m.textInput.Value()
 				m.textInput.Blur()
 				m.uiState = UIStateWaitingForInput
+				m.persistWorkspace()
+				return m, nil
+			}
+			if m.uiState == UIStateRenamingQuery {
+				if name := strings.TrimSpace(m.textInput.Value()); name != "" {
+					m.currentQueryData.name = name
+					m.persistWorkspace()
+				}
+				m.textInput.Blur()
+				m.uiState = UIStateSelectingQuery
+				return m, nil
+			}
+			if m.uiState == UIStateImportingCurl {
+				query, err := parseCurlCommand(m.textInput.Value())
+				m.textInput.Blur()
+				m.uiState = UIStateSelectingQuery
+				if err != nil {
+					m.statusMessage = fmt.Sprintf("couldn't import curl command: %s", err)
+					return m, nil
+				}
+				m.insertQuery(query)
+				m.persistWorkspace()
+				return m, nil
+			}
+			if m.uiState == UIStateEditingSetting {
+				if err := settingFields[m.focusedSetting].set(&m, strings.TrimSpace(m.textInput.Value())); err != nil {
+					m.statusMessage = err.Error()
+				} else {
+					m.persistWorkspace()
+				}
+				m.textInput.Blur()
+				m.uiState = UIStateWaitingForInput
+				return m, nil
+			}
+			if m.uiState == UIStateEditingCookie {
+				if c, ok := m.focusedJarCookie(); ok {
+					c.Value = m.textInput.Value()
+					m.cookieJar.update(c)
+					if err := m.saveCookieJar(); err != nil {
+						m.statusMessage = fmt.Sprintf("couldn't save cookies: %s", err)
+					}
+				}
+				m.textInput.Blur()
+				m.uiState = UIStateWaitingForInput
+				return m, nil
+			}
+			if m.uiState == UIStateEditingAuth {
+				fields := authFields(m.currentQueryData.auth.mode)
+				if err := fields[m.focusedAuthField].set(&m.currentQueryData.auth, strings.TrimSpace(m.textInput.Value())); err != nil {
+					m.statusMessage = err.Error()
+				} else {
+					m.persistWorkspace()
+				}
+				// changing the mode changes which fields there are
+				m.focusedAuthField = min(m.focusedAuthField, len(authFields(m.currentQueryData.auth.mode))-1)
+				m.textInput.Blur()
+				m.uiState = UIStateWaitingForInput
+				return m, nil
+			}
+			if m.uiState == UIStateEditingMethod {
+				method, err := parseHTTPMethod(m.textInput.Value())
+				if err != nil {
+					m.statusMessage = err.Error()
+				} else {
+					m.currentQueryData.requestMethod = method
+					m.persistWorkspace()
+				}
+				m.textInput.Blur()
+				m.uiState = UIStateWaitingForInput
 				return m, nil
 			}
 			if m.uiState == UIStateAddingHeader {
 				parsedValues := strings.Split(m.textInput.Value(), ":")
 				if len(parsedValues) == 2 {
 					m.currentQueryData.headers = append(m.currentQueryData.headers, HeaderData{name: parsedValues[0], value: parsedValues[1]})
+					m.persistWorkspace()
 				}
 				m.textInput.Blur()
 				m.uiState = UIStateWaitingForInput
@@ -408,6 +886,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				parsedValues := strings.Split(m.textInput.Value(), ":")
 				if len(parsedValues) == 2 {
 					m.currentQueryData.headers[m.focusedHeader] = HeaderData{name: parsedValues[0], value: parsedValues[1]}
+					m.persistWorkspace()
 				}
 				m.textInput.Blur()
 				m.uiState = UIStateWaitingForInput
@@ -417,6 +896,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				parsedValues := strings.Split(m.textInput.Value(), ":")
 				if len(parsedValues) == 2 {
 					m.currentQueryData.queryParams = append(m.currentQueryData.queryParams, QueryParamData{name: parsedValues[0], value: parsedValues[1]})
+					m.persistWorkspace()
 				}
 				m.textInput.Blur()
 				m.uiState = UIStateWaitingForInput
@@ -426,9 +906,101 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				parsedValues := strings.Split(m.textInput.Value(), ":")
 				if len(parsedValues) == 2 {
 					m.currentQueryData.queryParams[m.focusedParam] = QueryParamData{name: parsedValues[0], value: parsedValues[1]}
+					m.persistWorkspace()
+				}
+				m.textInput.Blur()
+				m.uiState = UIStateWaitingForInput
+				break
+			}
+			if m.uiState == UIStateAddingAssertion || m.uiState == UIStateEditingAssertion {
+				source := strings.TrimSpace(m.textInput.Value())
+				if source != "" {
+					if _, err := parseAssertion(source); err != nil {
+						m.statusMessage = err.Error()
+						return m, nil
+					}
+					if m.uiState == UIStateAddingAssertion {
+						m.currentQueryData.assertions = append(m.currentQueryData.assertions, source)
+						m.focusedAssertion = len(m.currentQueryData.assertions) - 1
+					} else {
+						m.currentQueryData.assertions[m.focusedAssertion] = source
+					}
+					m.persistWorkspace()
+				}
+				m.textInput.Blur()
+				m.uiState = UIStateWaitingForInput
+				return m, nil
+			}
+			if m.uiState == UIStateAddingExtraction || m.uiState == UIStateEditingExtraction {
+				source := strings.TrimSpace(m.textInput.Value())
+				if source != "" {
+					if _, err := parseExtraction(source); err != nil {
+						m.statusMessage = err.Error()
+						return m, nil
+					}
+					if m.uiState == UIStateAddingExtraction {
+						m.currentQueryData.extractions = append(m.currentQueryData.extractions, source)
+						m.focusedExtraction = len(m.currentQueryData.extractions) - 1
+					} else {
+						m.currentQueryData.extractions[m.focusedExtraction] = source
+					}
+					m.persistWorkspace()
+				}
+				m.textInput.Blur()
+				m.uiState = UIStateWaitingForInput
+				return m, nil
+			}
+			if m.uiState == UIStateAddingFormField || m.uiState == UIStateEditingFormField {
+				if strings.TrimSpace(m.textInput.Value()) != "" {
+					field, err := parseFormField(m.textInput.Value())
+					if err != nil {
+						m.statusMessage = err.Error()
+						return m, nil
+					}
+					if m.uiState == UIStateAddingFormField {
+						m.currentQueryData.formFields = append(m.currentQueryData.formFields, field)
+						m.focusedFormField = len(m.currentQueryData.formFields) - 1
+					} else {
+						m.currentQueryData.formFields[m.focusedFormField] = field
+					}
+					m.persistWorkspace()
 				}
 				m.textInput.Blur()
 				m.uiState = UIStateWaitingForInput
+				return m, nil
+			}
+			if m.currentTab == TabBody {
+				if m.focusedFormField < 0 || m.focusedFormField >= len(m.currentQueryData.formFields) {
+					break
+				}
+				m.uiState = UIStateEditingFormField
+				focusedField := m.currentQueryData.formFields[m.focusedFormField]
+				m.focusTextInputAndSetValue(focusedField.name + ":" + focusedField.value)
+				m.textInput.CursorEnd()
+				m.textInput.Placeholder = formFieldPlaceholder
+				if m.currentQueryData.bodyMode == bodyMultipart {
+					m.textInput.Placeholder = multipartFieldPlaceholder
+				}
+				break
+			}
+			if m.currentTab == TabExtract {
+				if m.focusedExtraction < 0 || m.focusedExtraction >= len(m.currentQueryData.extractions) {
+					break
+				}
+				m.uiState = UIStateEditingExtraction
+				m.focusTextInputAndSetValue(m.currentQueryData.extractions[m.focusedExtraction])
+				m.textInput.CursorEnd()
+				m.textInput.Placeholder = extractionPlaceholder
+				break
+			}
+			if m.currentTab == TabTests {
+				if m.focusedAssertion < 0 || m.focusedAssertion >= len(m.currentQueryData.assertions) {
+					break
+				}
+				m.uiState = UIStateEditingAssertion
+				m.focusTextInputAndSetValue(m.currentQueryData.assertions[m.focusedAssertion])
+				m.textInput.CursorEnd()
+				m.textInput.Placeholder = assertionPlaceholder
 				break
 			}
 			if m.currentTab == TabHeaders {
@@ -451,64 +1023,216 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.textInput.Placeholder = "Enter query parameter (ex: myID:2)"
 				break
 			}
+			if m.currentTab == TabAuth {
+				field := authFields(m.currentQueryData.auth.mode)[m.focusedAuthField]
+				m.uiState = UIStateEditingAuth
+				m.focusTextInputAndSetValue(field.get(m.currentQueryData.auth))
+				m.textInput.CursorEnd()
+				m.textInput.Placeholder = ""
+				if field.label == authModeField.label {
+					m.textInput.Placeholder = "none, basic, bearer, apikey or oauth2"
+				}
+				break
+			}
+			if m.currentTab == TabSettings {
+				m.uiState = UIStateEditingSetting
+				m.focusTextInputAndSetValue(settingFields[m.focusedSetting].get(m))
+				m.textInput.CursorEnd()
+				m.textInput.Placeholder = "Leave empty to use the default"
+				break
+			}
 			if m.currentTab == TabResponse {
+				// one request at a time, so the one in flight can still be cancelled
+				if m.inFlight {
+					if m.uiState != UIStateWaitingForResponse {
+						m.statusMessage = fmt.Sprintf("%s is still being sent, wait for it or press %s to cancel it", m.sentQuery.name, m.keys.CancelRequest.Help().Key)
+					}
+					return m, nil
+				}
+				// a typo in a JSON body would otherwise only show up as whatever error the server gives back
+				if query := resolveQuery(*m.currentQueryData, m.variables()); bodyIsJSON(query) {
+					if err := checkJSONBody(string(query.body)); err != nil {
+						m.uiState = UIStateConfirmingSend
+						m.statusMessage = err.Error()
+						return m, nil
+					}
+				}
 				m.uiState = UIStateWaitingForResponse
-				return m, sendRequestFromModel(m)
+				cmd := sendRequestFromModel(&m)
+				return m, cmd
+			}
+			if m.currentTab == TabCookies {
+				c, ok := m.focusedJarCookie()
+				if !ok {
+					break
+				}
+				m.uiState = UIStateEditingCookie
+				m.focusTextInputAndSetValue(c.Value)
+				m.textInput.CursorEnd()
+				m.textInput.Placeholder = "Enter the cookie's value"
+				break
+			}
+			if m.currentTab == TabHistory {
+				entry, ok := m.focusedHistoryEntry()
+				if !ok {
+					break
+				}
+				m.uiState = UIStateShowingHistoryEntry
+				m.viewport.SetContent(buildHistoryEntryString(entry))
+				m.viewport.GotoTop()
+				return m, nil
 			}
 		}
-		if key.Matches(msg, m.keys.TabRight) && !userIsEditingSomething(m) {
-			if m.currentTab == TabQueryParams {
-				m.currentTab = TabHeaders
+		if key.Matches(msg, m.keys.ReplayHistory) && m.currentTab == TabHistory && !m.inFlight &&
+			!userIsEditingSomething(m) {
+			entry, ok := m.focusedHistoryEntry()
+			if !ok {
 				return m, nil
 			}
-			if m.currentTab == TabHeaders {
-				m.currentTab = TabBody
+			if entry.RequestTruncated {
+				m.statusMessage = "the request body was cut off in the history, so it can't be sent again exactly"
 				return m, nil
 			}
-			if m.currentTab == TabBody {
-				m.currentTab = TabResponse
-				m.textarea.Blur()
+			m.uiState = UIStateWaitingForResponse
+			m.currentTab = TabResponse
+			cmd := sendQuery(&m, entry.queryData(entry.QueryName))
+			m.replaying = true
+			return m, cmd
+		}
+		if key.Matches(msg, m.keys.MarkDiff) && m.currentTab == TabHistory && !userIsEditingSomething(m) {
+			entry, ok := m.focusedHistoryEntry()
+			if !ok {
 				return m, nil
 			}
-			if m.currentTab == TabResponse {
-				m.currentTab = TabQueryParams
+			if entry.Error != "" {
+				m.statusMessage = "that request failed, so there's no response to diff"
 				return m, nil
 			}
+			m.markDiffSide(diffSideFromHistory(entry))
+			return m, nil
+		}
+		if key.Matches(msg, m.keys.RestoreHistory) && m.currentTab == TabHistory && !userIsEditingSomething(m) {
+			entry, ok := m.focusedHistoryEntry()
+			if !ok {
+				return m, nil
+			}
+			m.insertQuery(entry.queryData(entry.QueryName + " (from history)"))
+			m.persistWorkspace()
+			m.statusMessage = "saved as " + m.currentQueryData.name
+			return m, nil
+		}
+		if key.Matches(msg, m.keys.TabRight) && !userIsEditingSomething(m) {
+			m.switchTab(1)
+			return m, nil
 		}
 		if key.Matches(msg, m.keys.TabLeft) && !userIsEditingSomething(m) {
-			if m.currentTab == TabQueryParams {
-				m.currentTab = TabResponse
+			m.switchTab(-1)
+			return m, nil
+		}
+		if key.Matches(msg, m.keys.CycleResponseView) && m.uiState == UIStateShowingDiff && m.currentTab == TabResponse {
+			if !m.diffStructural && !canDiffAsJSON(m.diffSides[0], m.diffSides[1]) {
+				m.statusMessage = "both responses have to be JSON to diff them by path"
 				return m, nil
 			}
-			if m.currentTab == TabHeaders {
-				m.currentTab = TabQueryParams
+			m.diffStructural = !m.diffStructural
+			m.showDiff()
+			return m, nil
+		}
+		if key.Matches(msg, m.keys.CycleResponseView) && m.currentTab == TabResponse && !userIsEditingSomething(m) {
+			i := slices.Index(m.responseViews, m.currentView)
+			m.currentView = m.responseViews[(i+1)%len(m.responseViews)]
+			if m.uiState == UIStateShowingExport {
+				m.uiState = UIStateWaitingForInput
+			}
+			m.refreshViewport()
+			m.viewport.GotoTop()
+			return m, nil
+		}
+		if key.Matches(msg, m.keys.ToggleTreeView) && m.currentTab == TabResponse && !userIsEditingSomething(m) {
+			m.treeMode = !m.treeMode
+			// start the cursor at the top of what's on screen so it doesn't make the viewport jump
+			m.treeCursor = m.viewport.YOffset
+			m.refreshViewport()
+			return m, nil
+		}
+		if key.Matches(msg, m.keys.Search) && m.currentTab == TabResponse && m.uiState == UIStateShowingResponse {
+			m.uiState = UIStateSearchingResponse
+			m.focusTextInputAndSetValue(m.searchQuery)
+			m.textInput.CursorEnd()
+			m.textInput.Placeholder = "Search the response"
+			return m, nil
+		}
+		if key.Matches(msg, m.keys.FilterResponse) && m.currentTab == TabResponse && m.uiState == UIStateShowingResponse {
+			m.uiState = UIStateFilteringResponse
+			m.focusTextInputAndSetValue(m.responseFilter)
+			m.textInput.CursorEnd()
+			m.textInput.Placeholder = "Filter JSON with a path, leave empty to show everything"
+			return m, nil
+		}
+		if (key.Matches(msg, m.keys.NextMatch) || key.Matches(msg, m.keys.PrevMatch)) && m.currentTab == TabResponse && m.uiState == UIStateShowingResponse {
+			if key.Matches(msg, m.keys.NextMatch) {
+				m.gotoSearchMatch(m.currentMatch + 1)
+			} else {
+				m.gotoSearchMatch(m.currentMatch - 1)
+			}
+			return m, nil
+		}
+		if m.isNavigatingTree() {
+			if key.Matches(msg, m.keys.ListNext) {
+				m.moveTreeCursor(1)
 				return m, nil
 			}
-			if m.currentTab == TabBody {
-				m.currentTab = TabHeaders
-				m.textarea.Blur()
+			if key.Matches(msg, m.keys.ListPrev) {
+				m.moveTreeCursor(-1)
 				return m, nil
 			}
-			if m.currentTab == TabResponse {
-				m.currentTab = TabBody
-				m.textarea.Focus()
+			if key.Matches(msg, m.keys.ToggleFold) {
+				m.toggleFoldAtCursor()
 				return m, nil
 			}
+			if key.Matches(msg, m.keys.FoldAll) || key.Matches(msg, m.keys.UnfoldAll) {
+				// the first line is whatever the tree is showing from, which isn't the whole body when it's filtered
+				setAllFolded(m.treeLines[0].node, m.shownResponse().folded, key.Matches(msg, m.keys.FoldAll))
+				m.treeCursor = 0
+				m.refreshViewport()
+				m.viewport.GotoTop()
+				return m, nil
+			}
+		}
+		if key.Matches(msg, m.keys.CancelRequest) && m.inFlight && !userIsEditingSomething(m) {
+			if m.cancelRequest != nil {
+				m.cancelRequest()
+			}
+			m.recordHistory(m.sentQuery, nil, context.Canceled)
+			// whatever the request sends back now is too late to show
+			m.requestID++
+			m.inFlight = false
+			if m.uiState == UIStateWaitingForResponse {
+				m.uiState = UIStateRequestCancelled
+			} else {
+				m.statusMessage = fmt.Sprintf("cancelled %s", m.sentQuery.name)
+			}
+			return m, nil
 		}
 		if key.Matches(msg, m.keys.ListNext) {
 			if m.uiState == UIStateSelectingQuery {
 				if m.focusedQuery < len(m.queries)-1 {
-					m.focusedQuery += 1
-					m.textarea.SetValue(string(m.queries[m.focusedQuery].body))
-					if m.queries[m.focusedQuery].responseData != nil {
-						m.viewport.SetContent(m.queries[m.focusedQuery].responseData.body)
-					} else {
-						m.viewport.SetContent("")
-					}
-					m.currentQueryData = &m.queries[m.focusedQuery]
+					m.selectQuery(m.focusedQuery + 1)
 				}
 				return m, nil
 			}
+			if m.currentTab == TabCookies && !userIsEditingSomething(m) && m.focusedCookie < len(m.cookieJar.list())-1 {
+				m.focusedCookie += 1
+			}
+			if m.currentTab == TabHistory && !userIsEditingSomething(m) && m.uiState != UIStateShowingHistoryEntry && m.focusedHistory < len(m.history)-1 {
+				m.focusedHistory += 1
+			}
+			if m.currentTab == TabSettings && !userIsEditingSomething(m) && m.focusedSetting < len(settingFields)-1 {
+				m.focusedSetting += 1
+			}
+			if m.currentTab == TabAuth && !userIsEditingSomething(m) && m.focusedAuthField < len(authFields(m.currentQueryData.auth.mode))-1 {
+				m.focusedAuthField += 1
+			}
 			if m.currentTab == TabHeaders && !userIsEditingSomething(m) {
 				if m.focusedHeader < len(m.currentQueryData.headers)-1 {
 					m.focusedHeader += 1
@@ -518,6 +1242,30 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.textInput.Placeholder = "Enter header (ex. Accept:application/json;v=2)"
 				}
 			}
+			if m.currentTab == TabTests && !userIsEditingSomething(m) {
+				if m.focusedAssertion < len(m.currentQueryData.assertions)-1 {
+					m.focusedAssertion += 1
+				} else {
+					m.startAddingAssertion()
+				}
+			}
+			if m.currentTab == TabScripts && !userIsEditingSomething(m) {
+				m.showScript(true)
+			}
+			if m.currentTab == TabBody && usesFormFields(m.currentQueryData.bodyMode) && !userIsEditingSomething(m) {
+				if m.focusedFormField < len(m.currentQueryData.formFields)-1 {
+					m.focusedFormField += 1
+				} else {
+					m.startAddingFormField()
+				}
+			}
+			if m.currentTab == TabExtract && !userIsEditingSomething(m) {
+				if m.focusedExtraction < len(m.currentQueryData.extractions)-1 {
+					m.focusedExtraction += 1
+				} else {
+					m.startAddingExtraction()
+				}
+			}
 			if m.currentTab == TabQueryParams && !userIsEditingSomething(m) {
 				if m.focusedParam < len(m.currentQueryData.queryParams)-1 {
 					m.focusedParam += 1
@@ -531,14 +1279,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if key.Matches(msg, m.keys.ListPrev) {
 			if m.uiState == UIStateSelectingQuery {
 				if m.focusedQuery > 0 {
-					m.focusedQuery -= 1
-					m.textarea.SetValue(string(m.queries[m.focusedQuery].body))
-					if m.queries[m.focusedQuery].responseData != nil {
-						m.viewport.SetContent(m.queries[m.focusedQuery].responseData.body)
-					} else {
-						m.viewport.SetContent("")
-					}
-					m.currentQueryData = &m.queries[m.focusedQuery]
+					m.selectQuery(m.focusedQuery - 1)
 				}
 				return m, nil
 			}
@@ -548,6 +1289,30 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.currentTab == TabQueryParams && m.focusedParam > 0 && !userIsEditingSomething(m) {
 				m.focusedParam -= 1
 			}
+			if m.currentTab == TabTests && m.focusedAssertion > 0 && !userIsEditingSomething(m) {
+				m.focusedAssertion -= 1
+			}
+			if m.currentTab == TabBody && m.focusedFormField > 0 && !userIsEditingSomething(m) {
+				m.focusedFormField -= 1
+			}
+			if m.currentTab == TabExtract && m.focusedExtraction > 0 && !userIsEditingSomething(m) {
+				m.focusedExtraction -= 1
+			}
+			if m.currentTab == TabScripts && !userIsEditingSomething(m) {
+				m.showScript(false)
+			}
+			if m.currentTab == TabSettings && m.focusedSetting > 0 && !userIsEditingSomething(m) {
+				m.focusedSetting -= 1
+			}
+			if m.currentTab == TabAuth && m.focusedAuthField > 0 && !userIsEditingSomething(m) {
+				m.focusedAuthField -= 1
+			}
+			if m.currentTab == TabCookies && m.focusedCookie > 0 && !userIsEditingSomething(m) {
+				m.focusedCookie -= 1
+			}
+			if m.currentTab == TabHistory && m.focusedHistory > 0 && !userIsEditingSomething(m) && m.uiState != UIStateShowingHistoryEntry {
+				m.focusedHistory -= 1
+			}
 		}
 		if key.Matches(msg, m.keys.ListAdd) {
 			if m.currentTab == TabHeaders && !userIsEditingSomething(m) {
@@ -558,33 +1323,164 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.uiState = UIStateAddingQueryParam
 				m.focusTextInputAndSetValue("")
 			}
+			if m.currentTab == TabTests && !userIsEditingSomething(m) {
+				m.startAddingAssertion()
+				return m, nil
+			}
+			if m.currentTab == TabBody && usesFormFields(m.currentQueryData.bodyMode) && !userIsEditingSomething(m) {
+				m.startAddingFormField()
+				return m, nil
+			}
+			if m.currentTab == TabExtract && !userIsEditingSomething(m) {
+				m.startAddingExtraction()
+				return m, nil
+			}
 		}
 		if key.Matches(msg, m.keys.ListDelete) {
 			if m.currentTab == TabHeaders && !userIsEditingSomething(m) {
 				m.removeFocusedHeader()
+				m.persistWorkspace()
 			}
 			if m.currentTab == TabQueryParams && !userIsEditingSomething(m) {
 				m.removeFocusedQueryParam()
+				m.persistWorkspace()
+			}
+			if m.currentTab == TabTests && !userIsEditingSomething(m) {
+				m.removeFocusedAssertion()
+				m.persistWorkspace()
+			}
+			if m.currentTab == TabBody && usesFormFields(m.currentQueryData.bodyMode) && !userIsEditingSomething(m) {
+				m.removeFocusedFormField()
+				m.persistWorkspace()
+			}
+			if m.currentTab == TabExtract && !userIsEditingSomething(m) {
+				m.removeFocusedExtraction()
+				m.persistWorkspace()
+			}
+			if m.currentTab == TabScripts && !userIsEditingSomething(m) {
+				m.console = nil
+			}
+			if m.currentTab == TabCookies && !userIsEditingSomething(m) {
+				if c, ok := m.focusedJarCookie(); ok {
+					m.cookieJar.remove(c)
+					m.focusedCookie = max(0, min(m.focusedCookie, len(m.cookieJar.list())-1))
+					if err := m.saveCookieJar(); err != nil {
+						m.statusMessage = fmt.Sprintf("couldn't save cookies: %s", err)
+					}
+				}
+			}
+			if m.currentTab == TabAuth && m.currentQueryData.auth.mode == authOAuth2 && !userIsEditingSomething(m) {
+				delete(m.oauthTokens, m.currentQueryData.auth.resolve(m.variables()).oauthCacheKey())
+				m.statusMessage = "forgot the cached token"
 			}
 		}
+		if key.Matches(msg, m.keys.OpenInEditor) && m.currentTab == TabBody && !usesFormFields(m.currentQueryData.bodyMode) && !userIsEditingSomething(m) {
+			return m, editBodyInEditor(m)
+		}
+		if key.Matches(msg, m.keys.OpenInPager) && m.currentTab == TabResponse && !userIsEditingSomething(m) {
+			if response := m.shownResponse(); response == nil || response.err != nil {
+				m.statusMessage = "there's no response to open yet"
+				return m, nil
+			}
+			return m, viewResponseInPager(m)
+		}
+		// works while the body's being edited too, so it can be tidied up without leaving the textarea
+		if key.Matches(msg, m.keys.FormatJSONBody) && m.currentTab == TabBody && !usesFormFields(m.currentQueryData.bodyMode) {
+			m.toggleJSONFormat()
+			return m, nil
+		}
+		if key.Matches(msg, m.keys.CycleBodyMode) && m.currentTab == TabBody && !userIsEditingSomething(m) {
+			m.currentQueryData.bodyMode = nextBodyMode(m.currentQueryData.bodyMode)
+			m.focusedFormField = 0
+			m.persistWorkspace()
+			return m, nil
+		}
 		if key.Matches(msg, m.keys.EditURL) && !userIsEditingSomething(m) {
 			m.uiState = UIStateEditingURL
 			m.focusTextInputAndSetValue(m.currentQueryData.url)
 			m.textInput.Placeholder = "Enter URL to send request to"
 			return m, nil
 		}
+		if key.Matches(msg, m.keys.CycleMethod) && !userIsEditingSomething(m) {
+			m.currentQueryData.requestMethod = nextHTTPMethod(m.currentQueryData.requestMethod)
+			m.persistWorkspace()
+			return m, nil
+		}
+		if key.Matches(msg, m.keys.CycleEnvironment) && !userIsEditingSomething(m) {
+			m.cycleEnvironment()
+			m.statusMessage = "using " + m.environmentName()
+			m.persistWorkspace()
+			return m, nil
+		}
+		if (key.Matches(msg, m.keys.ExportCurl) || key.Matches(msg, m.keys.ExportGo)) && !userIsEditingSomething(m) {
+			export := exportAsCurl
+			if key.Matches(msg, m.keys.ExportGo) {
+				export = exportAsGo
+			}
+			query := resolveQuery(*m.currentQueryData, m.variables())
+			// OAuth2 tokens are only in the export if one's been fetched already
+			exported, err := export(applyAuth(query, m.oauthTokens[query.auth.oauthCacheKey()].accessToken))
+			if err != nil {
+				m.statusMessage = fmt.Sprintf("couldn't export request: %s", err)
+				return m, nil
+			}
+			m.viewport.SetContent(exported)
+			m.viewport.GotoTop()
+			m.uiState = UIStateShowingExport
+			m.currentTab = TabResponse
+			if err := clipboard.WriteAll(exported); err != nil {
+				m.statusMessage = fmt.Sprintf("couldn't copy to clipboard: %s", err)
+			} else {
+				m.statusMessage = "copied to clipboard"
+			}
+			return m, nil
+		}
+		if key.Matches(msg, m.keys.EditMethod) && !userIsEditingSomething(m) {
+			m.uiState = UIStateEditingMethod
+			m.focusTextInputAndSetValue(string(m.currentQueryData.requestMethod))
+			m.textInput.Placeholder = "Enter HTTP method (ex. PROPFIND)"
+			return m, nil
+		}
 		if key.Matches(msg, m.keys.UnfocusTextInput) {
 			if m.uiState == UIStateEditingBody {
 				m.textarea.Blur()
 				m.uiState = UIStateWaitingForInput
+				m.persistWorkspace()
+				return m, nil
+			}
+			if m.uiState == UIStateEditingScript {
+				m.scriptArea.Blur()
+				m.uiState = UIStateWaitingForInput
+				m.persistWorkspace()
 				return m, nil
 			}
 			if m.uiState == UIStateSelectingQuery {
 				m.uiState = UIStateWaitingForInput
 				return m, nil
 			}
-			if m.uiState == UIStateEditingURL || m.uiState == UIStateEditingHeader || m.uiState == UIStateAddingHeader ||
-				m.uiState == UIStateEditingQueryParam || m.uiState == UIStateAddingQueryParam {
+			if m.uiState == UIStateShowingExport || m.uiState == UIStateShowingHistoryEntry || m.uiState == UIStateShowingDiff {
+				m.refreshViewport()
+				m.uiState = UIStateWaitingForInput
+				return m, nil
+			}
+			if m.uiState == UIStateShowingResponse && m.searchQuery != "" {
+				m.clearSearch()
+				return m, nil
+			}
+			if m.uiState == UIStateShowingResponse && m.responseFilter != "" {
+				m.responseFilter = ""
+				m.treeCursor = 0
+				m.refreshViewport()
+				return m, nil
+			}
+			if m.uiState == UIStateRenamingQuery || m.uiState == UIStateImportingCurl {
+				m.textInput.Blur()
+				m.uiState = UIStateSelectingQuery
+				return m, nil
+			}
+			if m.uiState == UIStateEditingURL || m.uiState == UIStateEditingMethod || m.uiState == UIStateEditingSetting || m.uiState == UIStateEditingCookie || m.uiState == UIStateEditingAuth || m.uiState == UIStateEditingHeader || m.uiState == UIStateAddingHeader ||
+				m.uiState == UIStateEditingQueryParam || m.uiState == UIStateAddingQueryParam || m.uiState == UIStateAddingAssertion || m.uiState == UIStateEditingAssertion ||
+				m.uiState == UIStateAddingExtraction || m.uiState == UIStateEditingExtraction || m.uiState == UIStateAddingFormField || m.uiState == UIStateEditingFormField {
 				m.textInput.Blur()
 				m.uiState = UIStateWaitingForInput
 			}
@@ -618,78 +1514,361 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 	cmds = append(cmds, cmd)
 
+	m.scriptArea, cmd = m.scriptArea.Update(msg)
+	if m.currentTab == TabScripts && m.scriptArea.Focused() {
+		*m.currentScript() = m.scriptArea.Value()
+	}
+	cmds = append(cmds, cmd)
+
 	m.textInput, cmd = m.textInput.Update(msg)
 	cmds = append(cmds, cmd)
 
 	return m, tea.Batch(cmds...)
 }
 
-func sendRequestFromModel(m model) tea.Cmd {
-	return func() tea.Msg {
-		timeStart := time.Now()
-		req, err := http.NewRequest(string(m.currentQueryData.requestMethod), m.currentQueryData.url, bytes.NewBuffer(m.currentQueryData.body))
-		if err != nil {
-			return errMsg{err: err}
+func sendRequestFromModel(m *model) tea.Cmd {
+	m.replaying = false
+	m.replayResponse = nil
+	m.sentQueryIndex = m.focusedQuery
+	// resolved up front so edits made while waiting for the response don't race with the request being built
+	query := resolveQuery(*m.currentQueryData, m.variables())
+	accessToken := ""
+	if query.auth.mode == authOAuth2 {
+		token := m.oauthTokens[query.auth.oauthCacheKey()]
+		if !token.valid() {
+			// the query's sent once the token's back, see oauthTokenMsg
+			return fetchOAuthTokenForQuery(m, query)
 		}
+		accessToken = token.accessToken
+	}
+	return sendAuthorizedQuery(m, query, accessToken)
+}
 
-		for _, header := range m.currentQueryData.headers {
-			req.Header.Set(header.name, header.value)
-		}
+// sendAuthorizedQuery adds query's auth to it, with accessToken being its OAuth2 token if it uses one, and sends it
+// once its pre-request script has run.
+func sendAuthorizedQuery(m *model, query QueryData, accessToken string) tea.Cmd {
+	query = applyAuth(query, accessToken)
+	if strings.TrimSpace(query.preScript) != "" {
+		// the request's sent once the script's done, see preRequestScriptMsg
+		return preRequestScriptCmd(m, query)
+	}
+	return sendEncodedQuery(m, query)
+}
 
-		q := req.URL.Query()
-		for _, param := range m.currentQueryData.queryParams {
-			q.Add(param.name, param.value)
-		}
-		req.URL.RawQuery = q.Encode()
+// sendEncodedQuery builds query's body and sends it, for requests that are resolved and scripted.
+func sendEncodedQuery(m *model, query QueryData) tea.Cmd {
+	query, err := encodeBody(query)
+	if err != nil {
+		m.inFlight = false
+		m.uiState = UIStateWaitingForInput
+		m.statusMessage = fmt.Sprintf("couldn't build the body so the request wasn't sent: %s", err)
+		return nil
+	}
+	return sendQuery(m, query)
+}
 
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		if err != nil {
-			return errMsg{err: err}
-		}
-		defer resp.Body.Close()
+// sendQuery sends query as is with its own settings, for requests that are already resolved.
+func sendQuery(m *model, query QueryData) tea.Cmd {
+	timeout := queryTimeout(query, m.settings)
+	options := queryRequestOptions(query, m.settings, m.cookieJar)
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancelRequest = cancel
+	m.sentQuery = query
+	m.requestID++
+	m.inFlight = true
+	requestID := m.requestID
 
-		responseBodyByteSlice, _ := io.ReadAll(resp.Body)
-		contentType := resp.Header.Get("Content-Type")
-		var responseBodyString string
-		if contentType == "application/json" {
-			var responseBytesBuffer bytes.Buffer
-			json.Indent(&responseBytesBuffer, responseBodyByteSlice, "", "\t")
-			responseBodyString = responseBytesBuffer.String()
-		} else {
-			responseBodyString = string(responseBodyByteSlice)
+	return func() tea.Msg {
+		defer cancel()
+		response, err := sendWithTimeout(ctx, query, timeout, options)
+		if err != nil {
+			return errMsg{requestID: requestID, err: err}
 		}
+		return responseMsg{requestID: requestID, response: response}
+	}
+}
 
-		// simulates delay from downstream server so UI changes are slow enough to watch
-		ARTIFICIAL_LATENCY := time.Second
-		time.Sleep(ARTIFICIAL_LATENCY)
-
-		timeElapsedString := time.Since(timeStart).Round(time.Millisecond).String()
+// finishResponse runs the sent query's tests and extractions on its response and keeps it as that query's response.
+// The post-response script, if there is one, is returned to be run, since it runs after the extractions.
+func (m *model) finishResponse(response *ResponseData) tea.Cmd {
+	response.assertionResults = evaluateAssertions(m.sentQuery.assertions, response)
+	if len(response.assertionResults) > 0 {
+		m.statusMessage = fmt.Sprintf("tests: %d/%d passed", countPassed(response.assertionResults), len(response.assertionResults))
+	}
+	if summary := extractionSummary(runExtractions(m.sentQuery.extractions, response, m.runtimeVariables)); summary != "" {
+		m.appendStatus(summary)
+	}
+	if m.sentQueryIndex >= 0 {
+		m.queries[m.sentQueryIndex].responseData = response
+	}
+	if strings.TrimSpace(m.sentQuery.postScript) != "" {
+		return postResponseScriptCmd(m.sentQuery, response, m.variables())
+	}
+	return nil
+}
 
-		return responseMsg(&ResponseData{
-			status:      resp.Status,
-			header:      resp.Header,
-			body:        responseBodyString,
-			timeElapsed: timeElapsedString,
-		})
+// shownResponse is the response the Response tab shows: a replayed history entry's, or else the current query's.
+func (m model) shownResponse() *ResponseData {
+	if m.replayResponse != nil {
+		return m.replayResponse
 	}
+	return m.currentQueryData.responseData
 }
 
 func userIsEditingSomething(m model) bool {
 	return m.uiState == UIStateEditingURL ||
+		m.uiState == UIStateRenamingQuery ||
+		m.uiState == UIStateImportingCurl ||
+		m.uiState == UIStateEditingMethod ||
+		m.uiState == UIStateEditingSetting ||
+		m.uiState == UIStateEditingCookie ||
+		m.uiState == UIStateEditingAuth ||
 		m.uiState == UIStateAddingHeader ||
 		m.uiState == UIStateEditingHeader ||
 		m.uiState == UIStateAddingQueryParam ||
 		m.uiState == UIStateEditingQueryParam ||
-		m.uiState == UIStateEditingBody
+		m.uiState == UIStateEditingBody ||
+		m.uiState == UIStateEditingScript ||
+		m.uiState == UIStateAddingAssertion ||
+		m.uiState == UIStateEditingAssertion ||
+		m.uiState == UIStateAddingExtraction ||
+		m.uiState == UIStateEditingExtraction ||
+		m.uiState == UIStateAddingFormField ||
+		m.uiState == UIStateEditingFormField ||
+		m.uiState == UIStateSearchingResponse ||
+		m.uiState == UIStateFilteringResponse
+}
+
+// nextHTTPMethod returns the method after current in httpMethods. Custom methods aren't in the list, so cycling from
+// one starts back over at GET.
+func nextHTTPMethod(current HTTPMethod) HTTPMethod {
+	i := slices.Index(httpMethods, current)
+	return httpMethods[(i+1)%len(httpMethods)]
+}
+
+// parseHTTPMethod uppercases s and checks it's a valid HTTP token, since net/http rejects anything else when the request
+// is built and it's nicer to find out while typing it in.
+func parseHTTPMethod(s string) (HTTPMethod, error) {
+	method := strings.ToUpper(strings.TrimSpace(s))
+	if method == "" {
+		return "", fmt.Errorf("method can't be empty")
+	}
+	for _, r := range method {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("!#$%&'*+-.^_`|~", r)) {
+			return "", fmt.Errorf("invalid method %q: %q isn't allowed in a method name", method, r)
+		}
+	}
+	return HTTPMethod(method), nil
 }
 
 func (m *model) focusTextInputAndSetValue(s string) {
+	m.textInput.Width = textInputWidth
+	m.textInput.CharLimit = textInputCharLimit
 	m.textInput.SetValue(s)
 	m.textInput.Focus()
 	m.textInput.SetCursor(0)
 }
 
+// selectQuery makes m.queries[i] the query being edited. It has to be called again whenever m.queries is modified,
+// since inserting or deleting can move the query currentQueryData points at.
+func (m *model) selectQuery(i int) {
+	m.focusedQuery = i
+	m.currentQueryData = &m.queries[i]
+	m.focusedHeader = 0
+	m.focusedParam = 0
+	m.focusedAssertion = 0
+	m.focusedExtraction = 0
+	m.focusedFormField = 0
+	m.focusedAuthField = 0
+	m.textarea.SetValue(string(m.currentQueryData.body))
+	m.scriptArea.SetValue(*m.currentScript())
+	m.replayResponse = nil
+	m.treeCursor = 0
+	m.refreshViewport()
+}
+
+// refreshViewport shows the current query's response in the viewport, replacing whatever else was shown there.
+func (m *model) refreshViewport() {
+	response := m.shownResponse()
+	m.treeLines = nil
+	m.viewportLines = nil
+	if response == nil {
+		m.updateSearchMatches()
+		m.viewport.SetContent("")
+		return
+	}
+	if m.currentView == ResponseViewBody && response.jsonTree != nil {
+		if response.folded == nil {
+			response.folded = map[string]bool{}
+		}
+		// folds only apply in tree mode, otherwise there'd be no way to tell what's folded
+		folded := map[string]bool{}
+		if m.treeMode {
+			folded = response.folded
+		}
+		root := response.jsonTree
+		if m.responseFilter != "" {
+			var err error
+			root, err = filteredJSONTree(root, m.responseFilter)
+			if err != nil || root == nil {
+				m.viewportLines = []string{fmt.Sprintf("(nothing matches the filter %s, esc to clear it)", m.responseFilter)}
+				if err != nil {
+					m.viewportLines = []string{fmt.Sprintf("(couldn't filter with %s: %s)", m.responseFilter, err)}
+				}
+				m.updateSearchMatches()
+				m.renderViewport()
+				return
+			}
+		}
+		m.treeLines = renderJSONTree(root, folded, m.treeMode)
+		m.treeCursor = max(0, min(m.treeCursor, len(m.treeLines)-1))
+		for _, line := range m.treeLines {
+			m.viewportLines = append(m.viewportLines, line.plain)
+		}
+	} else {
+		m.viewportLines = strings.Split(strings.TrimSuffix(responseViewContent(response, m.currentView), "\n"), "\n")
+	}
+	m.updateSearchMatches()
+	m.renderViewport()
+}
+
+// renderViewport styles m.viewportLines for the viewport, highlighting search matches and the tree cursor.
+func (m *model) renderViewport() {
+	var s strings.Builder
+	matches := m.searchMatches
+	for i, line := range m.viewportLines {
+		base := responseBodyStyle
+		isCursor := m.treeMode && m.treeLines != nil && i == m.treeCursor
+		if isCursor {
+			base = tabOpenStyle
+		}
+
+		lineMatches := []searchMatch{}
+		current := -1
+		for len(matches) > 0 && matches[0].line == i {
+			if len(m.searchMatches)-len(matches) == m.currentMatch {
+				current = len(lineMatches)
+			}
+			lineMatches = append(lineMatches, matches[0])
+			matches = matches[1:]
+		}
+
+		switch {
+		case len(lineMatches) > 0:
+			s.WriteString(highlightSearchMatches(line, lineMatches, current, base))
+		case isCursor:
+			s.WriteString(tabOpenStyle.Render(line))
+		case m.treeLines != nil:
+			s.WriteString(m.treeLines[i].styled)
+		default:
+			s.WriteString(line)
+		}
+		s.WriteString("\n")
+	}
+	m.viewport.SetContent(s.String())
+}
+
+// isNavigatingTree is true when up/down should move the tree cursor instead of scrolling the viewport.
+func (m model) isNavigatingTree() bool {
+	return m.treeMode && m.treeLines != nil && m.currentTab == TabResponse && m.uiState == UIStateShowingResponse
+}
+
+// moveTreeCursor moves the cursor offset lines, scrolling just enough to keep it on screen.
+func (m *model) moveTreeCursor(offset int) {
+	m.treeCursor = max(0, min(m.treeCursor+offset, len(m.treeLines)-1))
+	m.renderViewport()
+	if m.treeCursor < m.viewport.YOffset {
+		m.viewport.SetYOffset(m.treeCursor)
+	} else if m.treeCursor >= m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(m.treeCursor - m.viewport.Height + 1)
+	}
+}
+
+// toggleFoldAtCursor folds or unfolds the object or array on the cursor's line. On a line inside one, like a string
+// value, it folds the object or array containing it.
+func (m *model) toggleFoldAtCursor() {
+	node := m.treeLines[m.treeCursor].node
+	if !isFoldable(node) {
+		node = node.parent
+	}
+	if node == nil {
+		return
+	}
+	folded := m.shownResponse().folded
+	if folded[node.path] {
+		delete(folded, node.path)
+	} else {
+		folded[node.path] = true
+	}
+	m.refreshViewport()
+	// folding from inside an object moves the cursor up to the folded line
+	for i, line := range m.treeLines {
+		// filtered trees show copies of nodes, so they're matched on path
+		if line.node.path == node.path {
+			m.moveTreeCursor(i - m.treeCursor)
+			break
+		}
+	}
+}
+
+// insertQuery adds query to the sidebar right below the focused query and selects it.
+func (m *model) insertQuery(query QueryData) {
+	m.queries = slices.Insert(m.queries, m.focusedQuery+1, query)
+	if m.sentQueryIndex > m.focusedQuery {
+		m.sentQueryIndex++
+	}
+	m.selectQuery(m.focusedQuery + 1)
+}
+
+func (m *model) deleteFocusedQuery() {
+	if len(m.queries) <= 1 {
+		return
+	}
+	m.queries = slices.Delete(m.queries, m.focusedQuery, m.focusedQuery+1)
+	switch {
+	case m.sentQueryIndex == m.focusedQuery:
+		m.sentQueryIndex = -1
+	case m.sentQueryIndex > m.focusedQuery:
+		m.sentQueryIndex--
+	}
+	m.selectQuery(min(m.focusedQuery, len(m.queries)-1))
+}
+
+func (m *model) startRenamingQuery() {
+	m.uiState = UIStateRenamingQuery
+	m.focusTextInputAndSetValue(m.currentQueryData.name)
+	m.textInput.CursorEnd()
+	// the name is edited in place in the sidebar, so the input has to fit there
+	m.textInput.Width = querySelectionTabWidth - 4
+	m.textInput.Placeholder = "Enter query name"
+}
+
+// copyQuery deep copies query so the copy's headers and params can be edited without changing the original. The
+// response isn't copied since it wasn't sent from the copy.
+func copyQuery(query QueryData, name string) QueryData {
+	query.name = name
+	query.body = slices.Clone(query.body)
+	query.headers = slices.Clone(query.headers)
+	query.queryParams = slices.Clone(query.queryParams)
+	query.assertions = slices.Clone(query.assertions)
+	query.extractions = slices.Clone(query.extractions)
+	query.formFields = slices.Clone(query.formFields)
+	query.responseData = nil
+	return query
+}
+
+// switchTab moves offset tabs over, wrapping around at either end.
+func (m *model) switchTab(offset int) {
+	i := slices.Index(m.tabs, m.currentTab)
+	m.currentTab = m.tabs[(i+offset+len(m.tabs))%len(m.tabs)]
+	m.textarea.Blur()
+	m.scriptArea.Blur()
+	if m.uiState == UIStateShowingHistoryEntry {
+		m.refreshViewport()
+		m.uiState = UIStateWaitingForInput
+	}
+}
+
 func (m *model) removeFocusedHeader() {
 	if len(m.currentQueryData.headers) == 0 {
 		return
@@ -700,6 +1879,61 @@ func (m *model) removeFocusedHeader() {
 	}
 }
 
+func (m *model) startAddingAssertion() {
+	m.uiState = UIStateAddingAssertion
+	m.focusTextInputAndSetValue("")
+	m.textInput.Placeholder = assertionPlaceholder
+}
+
+func (m *model) removeFocusedAssertion() {
+	if len(m.currentQueryData.assertions) == 0 {
+		return
+	}
+	m.currentQueryData.assertions = slices.Delete(m.currentQueryData.assertions, m.focusedAssertion, m.focusedAssertion+1)
+	if m.focusedAssertion == len(m.currentQueryData.assertions) {
+		m.focusedAssertion = max(0, len(m.currentQueryData.assertions)-1)
+	}
+}
+
+// currentScript is the script the Scripts tab is showing.
+func (m *model) currentScript() *string {
+	if m.showingPostScript {
+		return &m.currentQueryData.postScript
+	}
+	return &m.currentQueryData.preScript
+}
+
+func (m *model) showScript(postResponse bool) {
+	m.showingPostScript = postResponse
+	m.scriptArea.SetValue(*m.currentScript())
+}
+
+// appendStatus adds s to the status message after whatever's already there, for responses that have more than one
+// thing to say.
+func (m *model) appendStatus(s string) {
+	if m.statusMessage == "" {
+		m.statusMessage = s
+		return
+	}
+	m.statusMessage += ", " + s
+}
+
+func (m *model) startAddingExtraction() {
+	m.uiState = UIStateAddingExtraction
+	m.focusTextInputAndSetValue("")
+	m.textInput.Placeholder = extractionPlaceholder
+}
+
+func (m *model) removeFocusedExtraction() {
+	if len(m.currentQueryData.extractions) == 0 {
+		return
+	}
+	m.currentQueryData.extractions = slices.Delete(m.currentQueryData.extractions, m.focusedExtraction, m.focusedExtraction+1)
+	if m.focusedExtraction == len(m.currentQueryData.extractions) {
+		m.focusedExtraction = max(0, len(m.currentQueryData.extractions)-1)
+	}
+}
+
 func (m *model) removeFocusedQueryParam() {
 	if len(m.currentQueryData.queryParams) == 0 {
 		return
@@ -717,53 +1951,92 @@ func (m model) View() string {
 	switch m.currentTab {
 	case TabQueryParams:
 		s += buildQueryTabString(m)
+	case TabAuth:
+		s += buildAuthTabString(m)
 	case TabHeaders:
 		s += buildHeaderTabString(m)
 	case TabBody:
-		s += lipgloss.Place(m.mainTabWidth, m.bodyHeight, lipgloss.Left, lipgloss.Top, responseBodyStyle.Render(m.textarea.View()),
-			lipgloss.WithWhitespaceBackground(responseBodyStyle.GetBackground())) + "\n"
+		s += buildBodyTabString(m)
+	case TabTests:
+		s += buildTestsTabString(m)
+	case TabExtract:
+		s += buildExtractTabString(m)
+	case TabScripts:
+		s += buildScriptsTabString(m)
+	case TabSettings:
+		s += buildSettingsTabString(m)
 	case TabResponse:
 		s += buildResponseTabString(m)
+	case TabHistory:
+		s += buildHistoryTabString(m)
+	case TabCookies:
+		s += buildCookiesTabString(m)
 	}
-	// render UI state
-	s += lipgloss.Place(m.mainTabWidth, 1, lipgloss.Left, lipgloss.Top, tabClosedStyle.Render(" "+string(m.uiState)),
+	// render UI state, plus anything the last action wants to tell the user
+	statusString := " " + string(m.uiState)
+	if m.currentTab == TabResponse && m.searchStatus() != "" {
+		statusString += " | " + m.searchStatus()
+	}
+	if m.currentTab == TabBody && m.jsonBodyStatus() != "" {
+		statusString += " | " + m.jsonBodyStatus()
+	}
+	if m.statusMessage != "" {
+		statusString += " | " + m.statusMessage
+	}
+	s += lipgloss.Place(m.mainTabWidth, 1, lipgloss.Left, lipgloss.Top, tabClosedStyle.Render(statusString),
 		lipgloss.WithWhitespaceBackground(tabClosedStyle.GetBackground()))
 	// adds a one-column "border" between main tab/sidebar
 	s = lipgloss.JoinHorizontal(lipgloss.Top, s, " ", buildQuerySelectorSidebar(m))
 	// render help component at the bottom of the terminal
-	s = lipgloss.JoinVertical(lipgloss.Left, s, m.help.View(m.keys))
+	s = lipgloss.JoinVertical(lipgloss.Left, s, m.help.View(tabHelp{keyMap: m.keys, tab: m.currentTab}))
 	return lipgloss.Place(m.screenWidth, m.bodyHeight+5, lipgloss.Top, lipgloss.Left, s)
 }
 
 func buildTopBarString(m model) string {
 	topHeader := ""
 	responseString := ""
-	if (m.uiState == UIStateShowingResponse || m.uiState == UIStateSelectingQuery) && m.currentQueryData.responseData != nil {
+	response := m.shownResponse()
+	if (m.uiState == UIStateShowingResponse || m.uiState == UIStateSelectingQuery || m.uiState == UIStateSearchingResponse || m.uiState == UIStateFilteringResponse) &&
+		response != nil {
 		responseString += tabClosedStyle.Render(" -> ")
 
 		responseStyle := responseOKStyle
-		if m.currentQueryData.responseData.status != "" {
-			if m.currentQueryData.responseData.status[:1] == "4" {
+		if response.status != "" {
+			if response.status[:1] == "4" {
 				responseStyle = responseClientErrorStyle
 			}
-			if m.currentQueryData.responseData.status[:1] == "5" {
+			if response.status[:1] == "5" {
 				responseStyle = responseServerErrorStyle
 			}
 		}
-		responseString += responseStyle.Render(m.currentQueryData.responseData.status)
+		responseString += responseStyle.Render(response.status)
 
-		responseString += tabClosedStyle.Render(fmt.Sprintf(" %s", m.currentQueryData.responseData.timeElapsed))
+		responseString += tabClosedStyle.Render(fmt.Sprintf(" %s", response.timeElapsed))
 	}
-	if m.uiState == UIStateShowingRequestError && m.currentQueryData.responseData != nil {
+	if m.uiState == UIStateShowingRequestError && response != nil {
 		responseString += tabClosedStyle.Render(" -> ")
 		responseString += responseServerErrorStyle.Render("ERROR")
 	}
+	if m.uiState == UIStateRequestCancelled {
+		responseString += tabClosedStyle.Render(" -> ")
+		responseString += responseClientErrorStyle.Render("CANCELLED")
+	}
 
+	methodString := string(m.currentQueryData.requestMethod)
+	if m.uiState == UIStateEditingMethod {
+		methodString = m.textInput.View()
+	}
+	if m.uiState == UIStateImportingCurl {
+		methodString = "curl"
+	}
 	urlString := m.currentQueryData.url
-	if m.uiState == UIStateEditingURL {
+	if m.uiState == UIStateEditingURL || m.uiState == UIStateImportingCurl {
 		urlString = m.textInput.View()
+	} else if resolvedURL := resolveVariables(m.currentQueryData.url, m.variables()); resolvedURL != m.currentQueryData.url {
+		// previews what the placeholders resolve to in the current environment
+		urlString += fmt.Sprintf(" (%s)", resolvedURL)
 	}
-	topHeader += tabClosedStyle.Render(fmt.Sprintf(" %s %s%s", m.currentQueryData.requestMethod, urlString, responseString))
+	topHeader += tabClosedStyle.Render(fmt.Sprintf(" %s %s%s", methodString, urlString, responseString))
 	topHeader += "\n"
 	for _, tab := range m.tabs {
 		if tab == m.currentTab {
@@ -772,6 +2045,7 @@ func buildTopBarString(m model) string {
 			topHeader += tabClosedStyle.Render(fmt.Sprintf(" %s ", tab))
 		}
 	}
+	topHeader += tabClosedStyle.Render(fmt.Sprintf("  env: %s", m.environmentName()))
 	return lipgloss.Place(m.mainTabWidth, 2, lipgloss.Left, lipgloss.Top, tabClosedStyle.Render(topHeader),
 		lipgloss.WithWhitespaceBackground(tabClosedStyle.GetBackground()),
 	) + "\n"
@@ -802,6 +2076,9 @@ func buildQueryTabString(m model) string {
 	if m.uiState == UIStateAddingQueryParam {
 		queryTabString += m.textInput.View() + "\n"
 	}
+	if preview := authPreview(m.currentQueryData.auth, true); preview != "" {
+		queryTabString += tabClosedStyle.Render(fmt.Sprintf(" %s (from the Auth tab)", preview)) + "\n"
+	}
 
 	return lipgloss.Place(m.mainTabWidth, m.bodyHeight, lipgloss.Left, lipgloss.Top, responseBodyStyle.Render(queryTabString),
 		lipgloss.WithWhitespaceBackground(responseBodyStyle.GetBackground())) + "\n"
@@ -809,16 +2086,14 @@ func buildQueryTabString(m model) string {
 
 func buildHeaderTabString(m model) string {
 	headerTabString := ""
-	if len(m.currentQueryData.headers) == 0 {
+	if len(m.currentQueryData.headers) == 0 && authPreview(m.currentQueryData.auth, false) == "" {
 		headerTabString += "(no headers will be sent)\n"
 	}
 
 	for i, header := range m.currentQueryData.headers {
-		headerString := ""
-		if header.name != "Authorization" {
-			headerString += fmt.Sprintf(" %s: %s", header.name, header.value)
-		} else {
-			headerString += " Authorization: Bearer ********"
+		headerString := fmt.Sprintf(" %s: %s", header.name, header.value)
+		if strings.EqualFold(header.name, "Authorization") {
+			headerString = fmt.Sprintf(" %s: %s", header.name, maskAuthorization(header.value))
 		}
 		if i == m.focusedHeader {
 			if m.uiState == UIStateEditingHeader {
@@ -837,6 +2112,9 @@ func buildHeaderTabString(m model) string {
 	if m.uiState == UIStateAddingHeader {
 		headerTabString += m.textInput.View() + "\n"
 	}
+	if preview := authPreview(m.currentQueryData.auth, false); preview != "" {
+		headerTabString += tabClosedStyle.Render(fmt.Sprintf(" %s (from the Auth tab)", preview)) + "\n"
+	}
 	return lipgloss.Place(m.mainTabWidth, m.bodyHeight, lipgloss.Left, lipgloss.Top, responseBodyStyle.Render(headerTabString),
 		lipgloss.WithWhitespaceBackground(responseBodyStyle.GetBackground())) + "\n"
 }
@@ -847,13 +2125,26 @@ func buildResponseTabString(m model) string {
 	case UIStateWaitingForInput:
 		responseTabString = "response not yet sent\n"
 	case UIStateWaitingForResponse:
-		responseTabString = "waiting for response...\n"
+		responseTabString = fmt.Sprintf("waiting for response... (%s to cancel)\n", m.keys.CancelRequest.Help().Key)
+	case UIStateRequestCancelled:
+		responseTabString = "request cancelled\n"
 	case UIStateShowingResponse:
-		responseTabString = m.viewport.View()
+		responseTabString = buildResponseViewBar(m) + m.viewport.View()
 	case UIStateSelectingQuery:
+		responseTabString = buildResponseViewBar(m) + m.viewport.View()
+	case UIStateSearchingResponse:
+		// the search input takes the view bar's place so the viewport doesn't move while typing
+		searchMode := ""
+		if m.searchRegex {
+			searchMode = "regex"
+		}
+		responseTabString = searchMode + m.textInput.View() + "\n" + m.viewport.View()
+	case UIStateFilteringResponse:
+		responseTabString = m.textInput.View() + "\n" + m.viewport.View()
+	case UIStateShowingExport, UIStateShowingDiff:
 		responseTabString = m.viewport.View()
 	case UIStateShowingRequestError:
-		responseTabString = fmt.Sprintf("error occurred sending request: %s\n", m.currentQueryData.responseData.err)
+		responseTabString = fmt.Sprintf("error occurred sending request: %s\n", m.shownResponse().err)
 	}
 	return lipgloss.Place(m.mainTabWidth, m.bodyHeight, lipgloss.Left, lipgloss.Top, responseBodyStyle.Render(responseTabString),
 		lipgloss.WithWhitespaceBackground(responseBodyStyle.GetBackground())) + "\n"
@@ -865,9 +2156,11 @@ func buildQuerySelectorSidebar(m model) string {
 	querySelectorString := lipgloss.Place(querySelectionTabWidth-1, 1, lipgloss.Right, lipgloss.Top, tabClosedStyle.Render("\nsaved queries"),
 		lipgloss.WithWhitespaceBackground(tabClosedStyle.GetBackground())) + "\n"
 	for i, query := range m.queries {
-		if i == m.focusedQuery {
+		if i == m.focusedQuery && m.uiState == UIStateRenamingQuery {
+			querySelectorString += lipgloss.Place(querySelectionTabWidth-1, 1, lipgloss.Right, lipgloss.Top, m.textInput.View()) + "\n"
+		} else if i == m.focusedQuery {
 			focusedStyle := tabOpenStyle
-			if m.uiState != UIStateSelectingQuery {
+			if m.uiState != UIStateSelectingQuery && m.uiState != UIStateDeletingQuery {
 				focusedStyle = responseBodyStyle
 			}
 			querySelectorString += lipgloss.Place(querySelectionTabWidth-1, 1, lipgloss.Right, lipgloss.Top, focusedStyle.Render(query.name),
@@ -880,8 +2173,64 @@ func buildQuerySelectorSidebar(m model) string {
 }
 
 func main() {
-	if _, err := tea.NewProgram(initialModel()).Run(); err != nil {
+	if len(os.Args) > 1 && os.Args[1] == "import-curl" {
+		if err := importCurlCommand(os.Args[2:]); err != nil {
+			fmt.Printf("Uh oh, couldn't import the curl command: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		passed, err := runCommand(os.Args[2:], os.Stdout)
+		if err != nil {
+			fmt.Printf("Uh oh, couldn't run the workspace: %v\n", err)
+			os.Exit(2)
+		}
+		// failing checks exit with 1 so scripts can tell them apart from not being able to run at all
+		if !passed {
+			os.Exit(1)
+		}
+		return
+	}
+
+	workspacePath := flag.String("workspace", "workspace.json", "workspace file to load saved queries from and save them to")
+	flag.Parse()
+
+	ws, err := loadWorkspace(*workspacePath)
+	if err != nil {
+		fmt.Printf("Uh oh, couldn't load the workspace: %v\n", err)
+		os.Exit(1)
+	}
+
+	history, skippedHistory, err := loadHistory(historyPath(*workspacePath))
+	if err != nil {
+		fmt.Printf("Uh oh, couldn't load the request history: %v\n", err)
+		os.Exit(1)
+	}
+
+	jar := newCookieJar()
+	if ws.Settings.PersistCookies {
+		if jar, err = loadCookieJar(cookiesPath(*workspacePath)); err != nil {
+			fmt.Printf("Uh oh, couldn't load the saved cookies: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	m := initialModel(*workspacePath, ws, history, jar)
+	if skippedHistory > 0 {
+		m.statusMessage = fmt.Sprintf("skipped %d history entries that couldn't be read from %s", skippedHistory, historyPath(*workspacePath))
+	}
+	finalModel, err := tea.NewProgram(m).Run()
+	if err != nil {
 		fmt.Printf("Uh oh, there was an error: %v\n", err)
 		os.Exit(1)
 	}
+	// edits are saved as they're made, but save once more on the way out so nothing slips through the cracks
+	if m, ok := finalModel.(model); ok {
+		if err := saveWorkspace(*workspacePath, m.workspaceFile()); err != nil {
+			fmt.Printf("Uh oh, couldn't save the workspace: %v\n", err)
+			os.Exit(1)
+		}
+	}
 }
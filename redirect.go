@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// defaultMaxRedirects is how many redirects are followed unless a query says otherwise, the same as net/http's default.
+const defaultMaxRedirects = 10
+
+// redirectHop is one request in a redirect chain and the response it got.
+type redirectHop struct {
+	method   string
+	url      string
+	status   string
+	location string
+}
+
+// redirectRecorder is an http.Client's CheckRedirect, keeping track of every hop so the Response tab can show where a
+// request ended up and how it got there.
+type redirectRecorder struct {
+	maxRedirects int
+	hops         []redirectHop
+	// stopped is whether a redirect wasn't followed, because of the limit or because the query doesn't follow them
+	stopped bool
+}
+
+func (r *redirectRecorder) check(req *http.Request, via []*http.Request) error {
+	if len(via) > r.maxRedirects {
+		r.stopped = true
+		// the redirect itself is the response, rather than an error that would hide it
+		return http.ErrUseLastResponse
+	}
+	previous := via[len(via)-1]
+	r.hops = append(r.hops, redirectHop{
+		method:   previous.Method,
+		url:      previous.URL.String(),
+		status:   req.Response.Status,
+		location: req.Response.Header.Get("Location"),
+	})
+	return nil
+}
+
+// chain is every hop, ending with the request that got resp.
+func (r *redirectRecorder) chain(resp *http.Response) []redirectHop {
+	return append(r.hops, redirectHop{
+		method:   resp.Request.Method,
+		url:      resp.Request.URL.String(),
+		status:   resp.Status,
+		location: resp.Header.Get("Location"),
+	})
+}
+
+// parseMaxRedirects parses the redirects setting: empty for the default, off (or 0) to not follow them, or how many
+// to follow. Not following is saved as -1, so it can be told apart from the default.
+func parseMaxRedirects(s string) (int, error) {
+	switch s {
+	case "":
+		return 0, nil
+	case "off", "0":
+		return -1, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q should be off or how many redirects to follow (ex. 5)", s)
+	}
+	return n, nil
+}
+
+func formatMaxRedirects(n int) string {
+	switch {
+	case n < 0:
+		return "off"
+	case n == 0:
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func buildResponseRedirectsString(response *ResponseData) string {
+	if len(response.redirectChain) == 0 {
+		return "(no redirect information for this response)\n"
+	}
+	redirectsString := ""
+	if len(response.redirectChain) == 1 && !response.redirectStopped {
+		redirectsString += "(not redirected)\n\n"
+	}
+	for i, hop := range response.redirectChain {
+		redirectsString += fmt.Sprintf("%d. %s %s\n", i+1, hop.method, hop.url)
+		if hop.location != "" {
+			redirectsString += fmt.Sprintf("   %s, Location: %s\n", hop.status, hop.location)
+		} else {
+			redirectsString += fmt.Sprintf("   %s\n", hop.status)
+		}
+	}
+	if response.redirectStopped {
+		if len(response.redirectChain) == 1 {
+			redirectsString += "\n(the redirect wasn't followed, since this query doesn't follow redirects)\n"
+		} else {
+			redirectsString += fmt.Sprintf("\n(stopped after %d redirects, the most this query follows)\n", len(response.redirectChain)-1)
+		}
+	}
+	return redirectsString
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// buildRequest turns query into an http.Request. query should already have its variables resolved.
+func buildRequest(query QueryData) (*http.Request, error) {
+	req, err := http.NewRequest(string(query.requestMethod), query.url, bytes.NewBuffer(query.body))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, header := range query.headers {
+		req.Header.Set(header.name, header.value)
+	}
+
+	q := req.URL.Query()
+	for _, param := range query.queryParams {
+		q.Add(param.name, param.value)
+	}
+	req.URL.RawQuery = q.Encode()
+	return req, nil
+}
+
+// requestOptions are the settings that change how a request is sent rather than what's in it.
+type requestOptions struct {
+	simulatedLatency       time.Duration
+	simulatedLatencyJitter time.Duration
+	// jar is the workspace's cookie jar, or nil for queries that don't use it
+	jar *cookieJar
+	// maxRedirects is how many redirects are followed, with 0 meaning the first redirect is the response
+	maxRedirects int
+}
+
+// sendWithTimeout is executeRequest, giving up on the request after timeout unless it's 0.
+func sendWithTimeout(ctx context.Context, query QueryData, timeout time.Duration, options requestOptions) (*ResponseData, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	response, err := executeRequest(ctx, query, options)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("request timed out after %s", timeout)
+	}
+	return response, err
+}
+
+// executeRequest sends query and reads the whole response. Cancelling ctx (or letting it time out) stops the request
+// wherever it's at, including partway through reading the body.
+func executeRequest(ctx context.Context, query QueryData, options requestOptions) (*ResponseData, error) {
+	timeStart := time.Now()
+	req, err := buildRequest(query)
+	if err != nil {
+		return nil, err
+	}
+	tracer := &timingTracer{}
+	req = req.WithContext(httptrace.WithClientTrace(ctx, tracer.clientTrace()))
+
+	// simulates delay from a downstream server, for demos where UI changes should be slow enough to watch
+	latency := jitteredLatency(options.simulatedLatency, options.simulatedLatencyJitter)
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	redirects := &redirectRecorder{maxRedirects: options.maxRedirects}
+	client := &http.Client{CheckRedirect: redirects.check}
+	if options.jar != nil {
+		client.Jar = options.jar
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	responseBodyByteSlice, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	timeEnd := time.Now()
+	responseBodyString, formatErr := formatResponseBody(resp.Header.Get("Content-Type"), responseBodyByteSlice)
+
+	timing := tracer.timing(timeEnd)
+	timing.simulatedLatency = latency
+	timing.total = timeEnd.Sub(timeStart)
+
+	return &ResponseData{
+		status:      resp.Status,
+		header:      resp.Header,
+		cookies:     resp.Cookies(),
+		rawBody:     responseBodyByteSlice,
+		body:        responseBodyString,
+		formatErr:   formatErr,
+		jsonTree:    responseJSONTree(resp.Header.Get("Content-Type"), responseBodyByteSlice),
+		folded:      map[string]bool{},
+		timeElapsed: timing.total.Round(time.Millisecond).String(),
+		timing:      timing,
+		// resp.Request is the last request in the chain, which isn't req if there were redirects
+		redirectChain:   redirects.chain(resp),
+		redirectStopped: redirects.stopped,
+	}, nil
+}
+
+// responseTiming splits up where the time for a request went. Phases that didn't happen (ex. DNS and connecting when
+// a kept-alive connection was reused, or TLS for plain http) are 0.
+type responseTiming struct {
+	dnsLookup        time.Duration
+	connect          time.Duration
+	tlsHandshake     time.Duration
+	timeToFirstByte  time.Duration
+	download         time.Duration
+	simulatedLatency time.Duration
+	total            time.Duration
+	reusedConnection bool
+}
+
+// timingTracer records when each phase of a request starts and ends. The transport can call the hooks from its own
+// goroutines (ex. racing IPv4 and IPv6 connections), hence the mutex.
+type timingTracer struct {
+	mu                              sync.Mutex
+	dnsStart, dnsDone               time.Time
+	connectStart, connectDone       time.Time
+	tlsStart, tlsDone               time.Time
+	wroteRequest, firstResponseByte time.Time
+	reusedConnection                bool
+}
+
+func (t *timingTracer) record(at *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	*at = time.Now()
+}
+
+func (t *timingTracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.record(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.record(&t.dnsDone) },
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			// only the first attempt counts when several addresses are tried
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone:          func(string, string, error) { t.record(&t.connectDone) },
+		TLSHandshakeStart:    func() { t.record(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.record(&t.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.record(&t.wroteRequest) },
+		GotFirstResponseByte: func() { t.record(&t.firstResponseByte) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.reusedConnection = info.Reused
+		},
+	}
+}
+
+// timing works out how long each phase took, given when the body finished downloading.
+func (t *timingTracer) timing(bodyDone time.Time) responseTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return responseTiming{
+		dnsLookup:        between(t.dnsStart, t.dnsDone),
+		connect:          between(t.connectStart, t.connectDone),
+		tlsHandshake:     between(t.tlsStart, t.tlsDone),
+		timeToFirstByte:  between(t.wroteRequest, t.firstResponseByte),
+		download:         between(t.firstResponseByte, bodyDone),
+		reusedConnection: t.reusedConnection,
+	}
+}
+
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// jitteredLatency randomly moves latency up or down by as much as jitter, without going below 0.
+func jitteredLatency(latency, jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return latency
+	}
+	return max(0, latency+rand.N(2*jitter+1)-jitter)
+}
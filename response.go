@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ResponseView is one of the views inside the Response tab.
+type ResponseView string
+
+const (
+	ResponseViewBody      ResponseView = "Body"
+	ResponseViewHeaders   ResponseView = "Headers"
+	ResponseViewCookies   ResponseView = "Cookies"
+	ResponseViewTiming    ResponseView = "Timing"
+	ResponseViewRedirects ResponseView = "Redirects"
+	ResponseViewTests     ResponseView = "Tests"
+)
+
+// responseViewContent renders the part of response that view shows, for putting in the viewport.
+func responseViewContent(response *ResponseData, view ResponseView) string {
+	switch view {
+	case ResponseViewHeaders:
+		return buildResponseHeadersString(response)
+	case ResponseViewCookies:
+		return buildResponseCookiesString(response)
+	case ResponseViewTiming:
+		return buildResponseTimingString(response)
+	case ResponseViewRedirects:
+		return buildResponseRedirectsString(response)
+	case ResponseViewTests:
+		return buildResponseTestsString(response)
+	}
+	if response.formatErr != nil {
+		return fmt.Sprintf("(%s, showing the body as is)\n\n%s", response.formatErr, response.body)
+	}
+	return response.body
+}
+
+func buildResponseHeadersString(response *ResponseData) string {
+	if len(response.header) == 0 {
+		return "(no headers in response)\n"
+	}
+	names := []string{}
+	for name := range response.header {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	headersString := ""
+	for _, name := range names {
+		for _, value := range response.header[name] {
+			headersString += fmt.Sprintf("%s: %s\n", name, value)
+		}
+	}
+	return headersString
+}
+
+func buildResponseCookiesString(response *ResponseData) string {
+	if len(response.cookies) == 0 {
+		return "(no cookies set by response)\n"
+	}
+	cookiesString := ""
+	for _, cookie := range response.cookies {
+		cookiesString += fmt.Sprintf("%s = %s\n", cookie.Name, cookie.Value)
+		details := []string{}
+		if cookie.Domain != "" {
+			details = append(details, "domain "+cookie.Domain)
+		}
+		if cookie.Path != "" {
+			details = append(details, "path "+cookie.Path)
+		}
+		if !cookie.Expires.IsZero() {
+			details = append(details, "expires "+cookie.Expires.Local().Format(time.DateTime))
+		}
+		if cookie.MaxAge > 0 {
+			details = append(details, fmt.Sprintf("max age %ds", cookie.MaxAge))
+		}
+		if cookie.Secure {
+			details = append(details, "secure")
+		}
+		if cookie.HttpOnly {
+			details = append(details, "http only")
+		}
+		if len(details) > 0 {
+			cookiesString += "    " + strings.Join(details, ", ") + "\n"
+		}
+	}
+	return cookiesString
+}
+
+type timingPhase struct {
+	name     string
+	duration time.Duration
+}
+
+// buildResponseTimingString lists each phase of the request with a bar showing its share of the total time.
+func buildResponseTimingString(response *ResponseData) string {
+	const barWidth = 40
+	timing := response.timing
+	phases := []timingPhase{
+		{"DNS lookup", timing.dnsLookup},
+		{"TCP connect", timing.connect},
+		{"TLS handshake", timing.tlsHandshake},
+		{"time to first byte", timing.timeToFirstByte},
+		{"download", timing.download},
+	}
+	if timing.simulatedLatency > 0 {
+		phases = append(phases, timingPhase{"simulated latency", timing.simulatedLatency})
+	}
+
+	timingString := ""
+	for _, phase := range phases {
+		bar := ""
+		if timing.total > 0 {
+			bar = strings.Repeat("█", int(int64(barWidth)*int64(phase.duration)/int64(timing.total)))
+		}
+		timingString += fmt.Sprintf("%-20s %10s  %s\n", phase.name, formatPhaseDuration(phase.duration), bar)
+	}
+	timingString += fmt.Sprintf("%-20s %10s\n", "total", formatPhaseDuration(timing.total))
+	if timing.reusedConnection {
+		timingString += "\n(reused an existing connection, so there was no DNS lookup or connect)\n"
+	}
+	return timingString
+}
+
+// formatPhaseDuration rounds to a precision that's still useful for phases that take well under a millisecond.
+func formatPhaseDuration(d time.Duration) string {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond).String()
+	}
+	return d.Round(100 * time.Microsecond).String()
+}
+
+func buildResponseViewBar(m model) string {
+	viewBar := ""
+	for _, view := range m.responseViews {
+		if view == m.currentView {
+			viewBar += tabOpenStyle.Render(fmt.Sprintf(" %s ", view))
+		} else {
+			viewBar += tabClosedStyle.Render(fmt.Sprintf(" %s ", view))
+		}
+	}
+	if m.treeMode && m.treeLines != nil {
+		viewBar += tabClosedStyle.Render("  tree view")
+	}
+	if m.responseFilter != "" && m.currentView == ResponseViewBody {
+		response := m.shownResponse()
+		if response != nil && response.jsonTree == nil {
+			viewBar += tabClosedStyle.Render(fmt.Sprintf("  filter %s (body isn't JSON)", m.responseFilter))
+		} else {
+			viewBar += tabClosedStyle.Render("  filter " + m.responseFilter)
+		}
+	}
+	return viewBar + "\n"
+}
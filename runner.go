@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"strings"
+	"time"
+)
+
+// queryRun is how one query went in a headless run.
+type queryRun struct {
+	query    QueryData
+	response *ResponseData
+	err      error
+	results  []assertionResult
+	// extractions are the variables the response set for the queries after it
+	extractions []extractionResult
+	// console is what the query's scripts printed, and postScriptErr why its post-response script failed if it did
+	console       []string
+	postScriptErr error
+	elapsed       time.Duration
+}
+
+func (run queryRun) passed() bool {
+	return run.err == nil && countPassed(run.results) == len(run.results)
+}
+
+// runCommand sends every query in a workspace one after another and checks their assertions, for running collections
+// from scripts and CI. It returns whether everything passed; an error means the run couldn't happen at all.
+func runCommand(args []string, out io.Writer) (bool, error) {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	workspacePath := flags.String("workspace", "workspace.json", "workspace file with the queries to run")
+	envName := flags.String("env", "", "environment to resolve variables with (defaults to the workspace's active one)")
+	junitPath := flags.String("junit", "", "write a JUnit XML report to this file")
+	jsonPath := flags.String("json", "", "write a JSON report to this file")
+	flags.Parse(args)
+
+	ws, err := readWorkspaceFile(*workspacePath)
+	if err != nil {
+		return false, err
+	}
+	if len(ws.Queries) == 0 {
+		return false, fmt.Errorf("%s doesn't have any queries to run", *workspacePath)
+	}
+	environments := ws.environments()
+	if *envName == "" {
+		*envName = ws.ActiveEnvironment
+	}
+	vars := map[string]string{}
+	if *envName != "" {
+		i := environmentIndex(environments, *envName)
+		if i < 0 {
+			return false, fmt.Errorf("there's no environment named %q in %s", *envName, *workspacePath)
+		}
+		vars = environments[i].variables
+	}
+
+	settings := ws.settings()
+	runs := []queryRun{}
+	// values extracted from one response are used by every query after it, like they would be sending them one by one
+	extracted := map[string]string{}
+	// OAuth2 tokens are shared between queries using the same client, like they are in the TUI
+	tokens := map[string]oauthToken{}
+	// cookies are shared the same way, but a run always starts with an empty jar so it doesn't depend on past runs
+	jar := newCookieJar()
+	for _, query := range ws.queryData() {
+		run := runQuery(query, vars, extracted, tokens, jar, settings)
+		printQueryRun(out, run)
+		runs = append(runs, run)
+	}
+
+	passed := 0
+	for _, run := range runs {
+		if run.passed() {
+			passed++
+		}
+	}
+	fmt.Fprintf(out, "\n%d queries, %d passed, %d failed\n", len(runs), passed, len(runs)-passed)
+
+	if *junitPath != "" {
+		if err := writeReport(*junitPath, func(w io.Writer) error { return writeJUnitReport(w, *workspacePath, runs) }); err != nil {
+			return false, fmt.Errorf("couldn't write the JUnit report: %w", err)
+		}
+	}
+	if *jsonPath != "" {
+		if err := writeReport(*jsonPath, func(w io.Writer) error { return writeJSONReport(w, *workspacePath, *envName, runs) }); err != nil {
+			return false, fmt.Errorf("couldn't write the JSON report: %w", err)
+		}
+	}
+	return passed == len(runs), nil
+}
+
+// runQuery sends query the same way the TUI would, running its scripts and checking its assertions. It's resolved with
+// vars and the variables extracted so far, and whatever its extractions and scripts set is added to extracted.
+func runQuery(query QueryData, vars, extracted map[string]string, tokens map[string]oauthToken, jar *cookieJar, settings workspaceSettings) queryRun {
+	query = resolveQuery(query, mergeVariables(vars, extracted))
+	run := queryRun{query: query, console: []string{}}
+	if query.auth.mode == authOAuth2 {
+		token := tokens[query.auth.oauthCacheKey()]
+		if !token.valid() {
+			var err error
+			if token, err = fetchOAuthToken(context.Background(), query.auth, queryTimeout(query, settings)); err != nil {
+				run.err = fmt.Errorf("couldn't get an OAuth2 token: %w", err)
+				return run
+			}
+			tokens[query.auth.oauthCacheKey()] = token
+		}
+		query = applyAuth(query, token.accessToken)
+	} else {
+		query = applyAuth(query, "")
+	}
+	run.query = query
+	if strings.TrimSpace(query.preScript) != "" {
+		scripted, set, output, err := runPreRequestScript(query, mergeVariables(vars, extracted))
+		run.console = append(run.console, output...)
+		if err != nil {
+			run.err = fmt.Errorf("pre-request script failed: %w", err)
+			return run
+		}
+		maps.Copy(extracted, set)
+		query = scripted
+	}
+	query, err := encodeBody(query)
+	if err != nil {
+		run.err = fmt.Errorf("couldn't build the body: %w", err)
+		return run
+	}
+	run.query = query
+
+	start := time.Now()
+	run.response, run.err = sendWithTimeout(context.Background(), query, queryTimeout(query, settings), queryRequestOptions(query, settings, jar))
+	run.elapsed = time.Since(start)
+	if run.err != nil {
+		return run
+	}
+	run.results = evaluateAssertions(query.assertions, run.response)
+	run.extractions = runExtractions(query.extractions, run.response, extracted)
+	if strings.TrimSpace(query.postScript) != "" {
+		set, output, err := runPostResponseScript(query, run.response, mergeVariables(vars, extracted))
+		run.console = append(run.console, output...)
+		run.postScriptErr = err
+		if err == nil {
+			maps.Copy(extracted, set)
+		}
+	}
+	return run
+}
+
+func printQueryRun(out io.Writer, run queryRun) {
+	mark := "✓"
+	if !run.passed() {
+		mark = "✗"
+	}
+	if run.err != nil {
+		fmt.Fprintf(out, "%s %s  error: %s\n", mark, run.query.name, run.err)
+		printConsole(out, run.console)
+		return
+	}
+	line := fmt.Sprintf("%s %s  %s  %s", mark, run.query.name, run.response.status, run.response.timing.total.Round(time.Millisecond))
+	if len(run.results) > 0 {
+		line += fmt.Sprintf("  (%d/%d assertions passed)", countPassed(run.results), len(run.results))
+	}
+	fmt.Fprintln(out, line)
+	for _, result := range run.results {
+		if !result.passed {
+			fmt.Fprintf(out, "    ✗ %s (%s)\n", result.source, result.detail)
+		}
+	}
+	for _, result := range run.extractions {
+		if result.err != nil {
+			fmt.Fprintf(out, "    ! couldn't set %s (%s)\n", result.variable, result.err)
+		}
+	}
+	if run.postScriptErr != nil {
+		fmt.Fprintf(out, "    ! post-response script failed: %s\n", run.postScriptErr)
+	}
+	printConsole(out, run.console)
+}
+
+func printConsole(out io.Writer, console []string) {
+	for _, line := range console {
+		fmt.Fprintf(out, "    | %s\n", line)
+	}
+}
+
+// writeReport builds the whole report before writing it to path, so failing partway through doesn't leave half a report.
+func writeReport(path string, write func(w io.Writer) error) error {
+	var s strings.Builder
+	if err := write(&s); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(s.String()), 0o644)
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnitReport writes runs as a JUnit XML test suite, with each query as a test case. Failed assertions are
+// failures and requests that couldn't be sent at all are errors, which is how CI systems tell them apart.
+func writeJUnitReport(w io.Writer, workspacePath string, runs []queryRun) error {
+	suite := junitTestSuite{Name: workspacePath, Tests: len(runs)}
+	var total time.Duration
+	for _, run := range runs {
+		total += run.elapsed
+		testCase := junitTestCase{
+			Name:      run.query.name,
+			Classname: workspacePath,
+			Time:      fmt.Sprintf("%.3f", run.elapsed.Seconds()),
+		}
+		if run.err != nil {
+			suite.Errors++
+			testCase.Error = &junitProblem{Message: run.err.Error()}
+		} else if !run.passed() {
+			suite.Failures++
+			failed := []string{}
+			for _, result := range run.results {
+				if !result.passed {
+					failed = append(failed, fmt.Sprintf("%s (%s)", result.source, result.detail))
+				}
+			}
+			testCase.Failure = &junitProblem{
+				Message: fmt.Sprintf("%d of %d assertions failed", len(failed), len(run.results)),
+				Text:    strings.Join(failed, "\n"),
+			}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	suite.Time = fmt.Sprintf("%.3f", total.Seconds())
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type jsonReport struct {
+	Workspace   string            `json:"workspace"`
+	Environment string            `json:"environment,omitempty"`
+	Passed      int               `json:"passed"`
+	Failed      int               `json:"failed"`
+	Queries     []jsonQueryReport `json:"queries"`
+}
+
+type jsonQueryReport struct {
+	Name       string                `json:"name"`
+	Method     string                `json:"method"`
+	URL        string                `json:"url"`
+	Passed     bool                  `json:"passed"`
+	Status     string                `json:"status,omitempty"`
+	Error      string                `json:"error,omitempty"`
+	DurationMs int64                 `json:"durationMs"`
+	Assertions []jsonAssertionReport `json:"assertions,omitempty"`
+}
+
+type jsonAssertionReport struct {
+	Assertion string `json:"assertion"`
+	Passed    bool   `json:"passed"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+func writeJSONReport(w io.Writer, workspacePath, envName string, runs []queryRun) error {
+	report := jsonReport{Workspace: workspacePath, Environment: envName, Queries: []jsonQueryReport{}}
+	for _, run := range runs {
+		queryReport := jsonQueryReport{
+			Name:       run.query.name,
+			Method:     string(run.query.requestMethod),
+			URL:        run.query.url,
+			Passed:     run.passed(),
+			DurationMs: run.elapsed.Milliseconds(),
+		}
+		if req, err := buildRequest(run.query); err == nil {
+			queryReport.URL = req.URL.String()
+		}
+		if run.err != nil {
+			queryReport.Error = run.err.Error()
+		} else {
+			queryReport.Status = run.response.status
+		}
+		for _, result := range run.results {
+			assertionReport := jsonAssertionReport{Assertion: result.source, Passed: result.passed}
+			if !result.passed {
+				assertionReport.Detail = result.detail
+			}
+			queryReport.Assertions = append(queryReport.Assertions, assertionReport)
+		}
+		if queryReport.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Queries = append(report.Queries, queryReport)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(report)
+}
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	lipgloss "github.com/charmbracelet/lipgloss"
+	starlarkjson "go.starlark.net/lib/json"
+	starlarktime "go.starlark.net/lib/time"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+)
+
+// scripts are stopped after this many steps, so one stuck in a loop can't hang the program
+const maxScriptSteps = 10_000_000
+
+// the console only keeps this many lines, dropping the oldest
+const maxConsoleLines = 1000
+
+// scripts are written in Starlark, a small dialect of Python that can't touch the filesystem or network unless it's
+// given functions that do, and this doesn't give it any. Loops are allowed since the step limit keeps them in check.
+var scriptFileOptions = &syntax.FileOptions{Set: true, While: true, TopLevelControl: true, GlobalReassign: true}
+
+// scriptRequest is the request as pre-request scripts see it. Unlike the rest of what scripts are given, its fields
+// can be set, ex. request.url = request.url + "?ts=" + str(time.now().unix).
+type scriptRequest struct {
+	fields starlark.StringDict
+	frozen bool
+}
+
+func (r *scriptRequest) String() string {
+	return fmt.Sprintf("<request %s %s>", r.fields["method"].(starlark.String).GoString(), r.fields["url"].(starlark.String).GoString())
+}
+func (r *scriptRequest) Type() string          { return "request" }
+func (r *scriptRequest) Truth() starlark.Bool  { return true }
+func (r *scriptRequest) Hash() (uint32, error) { return 0, errors.New("unhashable type: request") }
+func (r *scriptRequest) AttrNames() []string   { return r.fields.Keys() }
+
+func (r *scriptRequest) Freeze() {
+	r.frozen = true
+	r.fields.Freeze()
+}
+
+func (r *scriptRequest) Attr(name string) (starlark.Value, error) {
+	return r.fields[name], nil
+}
+
+func (r *scriptRequest) SetField(name string, value starlark.Value) error {
+	if r.frozen {
+		return errors.New("request can't be changed anymore")
+	}
+	current, ok := r.fields[name]
+	if !ok {
+		return starlark.NoSuchAttrError(fmt.Sprintf("request has no .%s field", name))
+	}
+	if value.Type() != current.Type() {
+		return fmt.Errorf("request.%s has to be a %s, not a %s", name, current.Type(), value.Type())
+	}
+	r.fields[name] = value
+	return nil
+}
+
+// runPreRequestScript runs query's pre-request script on query, which should already be resolved, before it's sent.
+// It returns the request as the script left it, resolved again so placeholders for variables the script set (ex.
+// {{signature}}) are filled in, along with the variables it set and what it printed.
+func runPreRequestScript(query QueryData, vars map[string]string) (QueryData, map[string]string, []string, error) {
+	headers, params := headerPairs(query.headers), paramPairs(query.queryParams)
+	request := &scriptRequest{fields: starlark.StringDict{
+		"method":  starlark.String(query.requestMethod),
+		"url":     starlark.String(query.url),
+		"body":    starlark.String(query.body),
+		"headers": pairsDict(headers),
+		"params":  pairsDict(params),
+	}}
+	set, output, err := runScript(query.name+" pre-request", query.preScript, starlark.StringDict{"request": request}, vars)
+	if err != nil {
+		return query, set, output, err
+	}
+
+	method, err := parseHTTPMethod(string(request.fields["method"].(starlark.String)))
+	if err != nil {
+		return query, set, output, fmt.Errorf("request.method: %w", err)
+	}
+	query.requestMethod = method
+	query.url = string(request.fields["url"].(starlark.String))
+	query.body = []byte(request.fields["body"].(starlark.String))
+	// headers and params are only rebuilt if the script changed them, since a dict can't hold two with the same name
+	if changed, err := dictPairs(request.fields["headers"].(*starlark.Dict)); err != nil {
+		return query, set, output, fmt.Errorf("request.headers: %w", err)
+	} else if !slices.Equal(changed, dedupePairs(headers)) {
+		query.headers = []HeaderData{}
+		for _, pair := range changed {
+			query.headers = append(query.headers, HeaderData{name: pair[0], value: pair[1]})
+		}
+	}
+	if changed, err := dictPairs(request.fields["params"].(*starlark.Dict)); err != nil {
+		return query, set, output, fmt.Errorf("request.params: %w", err)
+	} else if !slices.Equal(changed, dedupePairs(params)) {
+		query.queryParams = []QueryParamData{}
+		for _, pair := range changed {
+			query.queryParams = append(query.queryParams, QueryParamData{name: pair[0], value: pair[1]})
+		}
+	}
+	return resolveQuery(query, mergeVariables(vars, set)), set, output, nil
+}
+
+// runPostResponseScript runs query's post-response script with the response it got, returning the variables it set
+// and what it printed.
+func runPostResponseScript(query QueryData, response *ResponseData, vars map[string]string) (map[string]string, []string, error) {
+	code, _, _ := strings.Cut(response.status, " ")
+	statusCode, _ := strconv.Atoi(code)
+	headers := starlark.NewDict(len(response.header))
+	names := slices.Sorted(maps.Keys(response.header))
+	for _, name := range names {
+		headers.SetKey(starlark.String(name), starlark.String(strings.Join(response.header[name], ", ")))
+	}
+	var body starlark.Value = starlark.None
+	root := response.jsonTree
+	if root == nil {
+		root, _ = parseJSONTree(response.rawBody)
+	}
+	if root != nil {
+		body = starlarkValue(root)
+	}
+	scriptResponse := starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"status":      starlark.String(response.status),
+		"status_code": starlark.MakeInt(statusCode),
+		"headers":     headers,
+		"body":        starlark.String(response.rawBody),
+		"json":        body,
+		"time_ms":     starlark.Float(float64(response.timing.total.Microseconds()) / 1000),
+	})
+	return runScript(query.name+" post-response", query.postScript, starlark.StringDict{"response": scriptResponse}, vars)
+}
+
+// runScript runs src with predeclared and the builtins every script gets. vars is given to the script as a dict, and
+// whatever it adds or changes there is returned as the variables it set.
+func runScript(name, src string, predeclared starlark.StringDict, vars map[string]string) (map[string]string, []string, error) {
+	output := []string{}
+	thread := &starlark.Thread{
+		Name:  name,
+		Print: func(_ *starlark.Thread, msg string) { output = append(output, msg) },
+		// load() would let scripts read files, so there's nothing to load from
+		Load: func(_ *starlark.Thread, module string) (starlark.StringDict, error) {
+			return nil, fmt.Errorf("scripts can't load %s", module)
+		},
+	}
+	thread.SetMaxExecutionSteps(maxScriptSteps)
+	thread.OnMaxSteps = func(thread *starlark.Thread) {
+		thread.Cancel(fmt.Sprintf("script took more than %d steps, is it stuck in a loop?", maxScriptSteps))
+	}
+
+	scriptVars := starlark.NewDict(len(vars))
+	for _, key := range slices.Sorted(maps.Keys(vars)) {
+		scriptVars.SetKey(starlark.String(key), starlark.String(vars[key]))
+	}
+	globals := starlark.StringDict{
+		"vars":          scriptVars,
+		"time":          starlarktime.Module,
+		"json":          starlarkjson.Module,
+		"sha256":        starlark.NewBuiltin("sha256", scriptSHA256),
+		"hmac_sha256":   starlark.NewBuiltin("hmac_sha256", scriptHMACSHA256),
+		"base64_encode": starlark.NewBuiltin("base64_encode", scriptBase64Encode),
+		"base64_decode": starlark.NewBuiltin("base64_decode", scriptBase64Decode),
+		"uuid4":         starlark.NewBuiltin("uuid4", scriptUUID4),
+	}
+	maps.Copy(globals, predeclared)
+
+	_, err := starlark.ExecFileOptions(scriptFileOptions, thread, name, src, globals)
+	set := map[string]string{}
+	for _, item := range scriptVars.Items() {
+		key, ok := starlark.AsString(item[0])
+		if !ok {
+			continue
+		}
+		// anything that isn't a string is set as it'd print, so vars["ts"] = time.now().unix works
+		value, ok := starlark.AsString(item[1])
+		if !ok {
+			value = item[1].String()
+		}
+		if current, ok := vars[key]; !ok || current != value {
+			set[key] = value
+		}
+	}
+	return set, output, err
+}
+
+func scriptSHA256(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var message, encoding string = "", "hex"
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "message", &message, "encoding?", &encoding); err != nil {
+		return nil, err
+	}
+	return encodeDigest(b.Name(), sha256.New(), message, encoding)
+}
+
+func scriptHMACSHA256(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var secret, message, encoding string = "", "", "hex"
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "key", &secret, "message", &message, "encoding?", &encoding); err != nil {
+		return nil, err
+	}
+	return encodeDigest(b.Name(), hmac.New(sha256.New, []byte(secret)), message, encoding)
+}
+
+// encodeDigest hashes message with h, encoded as hex or base64 since APIs asking for signatures want either.
+func encodeDigest(name string, h hash.Hash, message, encoding string) (starlark.Value, error) {
+	h.Write([]byte(message))
+	switch encoding {
+	case "hex":
+		return starlark.String(hex.EncodeToString(h.Sum(nil))), nil
+	case "base64":
+		return starlark.String(base64.StdEncoding.EncodeToString(h.Sum(nil))), nil
+	}
+	return nil, fmt.Errorf("%s: encoding has to be \"hex\" or \"base64\", not %q", name, encoding)
+}
+
+func scriptBase64Encode(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var s string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &s); err != nil {
+		return nil, err
+	}
+	return starlark.String(base64.StdEncoding.EncodeToString([]byte(s))), nil
+}
+
+func scriptBase64Decode(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var s string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &s); err != nil {
+		return nil, err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+	return starlark.String(decoded), nil
+}
+
+func scriptUUID4(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+	return starlark.String(fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:])), nil
+}
+
+// starlarkValue converts a JSON node to what it'd be if it were decoded in a script: objects become dicts, arrays
+// become lists and null becomes None.
+func starlarkValue(node *jsonNode) starlark.Value {
+	switch node.kind {
+	case jsonObject:
+		dict := starlark.NewDict(len(node.children))
+		for _, child := range node.children {
+			dict.SetKey(starlark.String(child.key), starlarkValue(child))
+		}
+		return dict
+	case jsonArray:
+		elems := []starlark.Value{}
+		for _, child := range node.children {
+			elems = append(elems, starlarkValue(child))
+		}
+		return starlark.NewList(elems)
+	case jsonString:
+		return starlark.String(node.value.(string))
+	case jsonNumber:
+		number := node.value.(json.Number)
+		if i, err := number.Int64(); err == nil {
+			return starlark.MakeInt64(i)
+		}
+		f, _ := number.Float64()
+		return starlark.Float(f)
+	case jsonBool:
+		return starlark.Bool(node.value.(bool))
+	}
+	return starlark.None
+}
+
+func headerPairs(headers []HeaderData) [][2]string {
+	pairs := [][2]string{}
+	for _, header := range headers {
+		pairs = append(pairs, [2]string{header.name, header.value})
+	}
+	return pairs
+}
+
+func paramPairs(params []QueryParamData) [][2]string {
+	pairs := [][2]string{}
+	for _, param := range params {
+		pairs = append(pairs, [2]string{param.name, param.value})
+	}
+	return pairs
+}
+
+func pairsDict(pairs [][2]string) *starlark.Dict {
+	dict := starlark.NewDict(len(pairs))
+	for _, pair := range pairs {
+		dict.SetKey(starlark.String(pair[0]), starlark.String(pair[1]))
+	}
+	return dict
+}
+
+// dedupePairs is what pairs looks like after going through a dict, where a later name replaces an earlier one.
+func dedupePairs(pairs [][2]string) [][2]string {
+	pairs, _ = dictPairs(pairsDict(pairs))
+	return pairs
+}
+
+func dictPairs(dict *starlark.Dict) ([][2]string, error) {
+	pairs := [][2]string{}
+	for _, item := range dict.Items() {
+		name, ok := starlark.AsString(item[0])
+		if !ok {
+			return nil, fmt.Errorf("names have to be strings, not %s", item[0].Type())
+		}
+		value, ok := starlark.AsString(item[1])
+		if !ok {
+			value = item[1].String()
+		}
+		pairs = append(pairs, [2]string{name, value})
+	}
+	return pairs, nil
+}
+
+// preRequestScriptMsg is sent when a pre-request script finishes, with query being the request as the script left it.
+// requestID is the request the script is for, like responseMsg's.
+type preRequestScriptMsg struct {
+	requestID int
+	query     QueryData
+	set       map[string]string
+	output    []string
+	err       error
+}
+
+type postResponseScriptMsg struct {
+	name   string
+	set    map[string]string
+	output []string
+	err    error
+}
+
+// preRequestScriptCmd runs query's pre-request script outside of Update, so a slow script doesn't freeze the UI. It
+// counts as the request being sent, so it can be cancelled (the script still runs, but the request isn't sent).
+func preRequestScriptCmd(m *model, query QueryData) tea.Cmd {
+	m.sentQuery = query
+	m.requestID++
+	m.inFlight = true
+	requestID := m.requestID
+	vars := m.variables()
+
+	return func() tea.Msg {
+		scripted, set, output, err := runPreRequestScript(query, vars)
+		return preRequestScriptMsg{requestID: requestID, query: scripted, set: set, output: output, err: err}
+	}
+}
+
+// postResponseScriptCmd runs query's post-response script on response outside of Update.
+func postResponseScriptCmd(query QueryData, response *ResponseData, vars map[string]string) tea.Cmd {
+	return func() tea.Msg {
+		set, output, err := runPostResponseScript(query, response, vars)
+		return postResponseScriptMsg{name: query.name + " post-response", set: set, output: output, err: err}
+	}
+}
+
+type consoleLine struct {
+	text    string
+	isError bool
+}
+
+// logScript adds what a script printed to the console, along with the error that stopped it if there was one.
+func (m *model) logScript(name string, output []string, err error) {
+	for _, line := range output {
+		m.console = append(m.console, consoleLine{text: fmt.Sprintf("[%s] %s", name, line)})
+	}
+	if err != nil {
+		message := err.Error()
+		var evalErr *starlark.EvalError
+		if errors.As(err, &evalErr) {
+			message = evalErr.Backtrace()
+		}
+		for _, line := range strings.Split(message, "\n") {
+			m.console = append(m.console, consoleLine{text: fmt.Sprintf("[%s] %s", name, line), isError: true})
+		}
+	}
+	if len(m.console) > maxConsoleLines {
+		m.console = slices.Delete(m.console, 0, len(m.console)-maxConsoleLines)
+	}
+}
+
+var scriptErrorStyle = responseBodyStyle.Foreground(lipgloss.Color("#f25c54"))
+
+// scriptName names each script where it's shown, ex. in the console and the Scripts tab.
+func scriptName(postResponse bool) string {
+	if postResponse {
+		return "post-response"
+	}
+	return "pre-request"
+}
+
+func buildScriptsTabString(m model) string {
+	scriptsTabString := ""
+	for _, postResponse := range []bool{false, true} {
+		label := fmt.Sprintf(" %s script ", scriptName(postResponse))
+		if postResponse == m.showingPostScript {
+			scriptsTabString += tabOpenStyle.Render(label)
+		} else {
+			scriptsTabString += label
+		}
+		scriptsTabString += " "
+	}
+	scriptsTabString += "\n" + m.scriptArea.View() + "\n"
+
+	scriptsTabString += fmt.Sprintf("console (%s to clear)\n", m.keys.ListDelete.Help().Key)
+	rows := consoleHeight(m.bodyHeight)
+	if len(m.console) == 0 {
+		scriptsTabString += " (nothing printed yet, print() in a script shows up here)\n"
+	}
+	for _, line := range m.console[max(0, len(m.console)-rows):] {
+		text := truncateString(line.text, m.mainTabWidth-1)
+		if line.isError {
+			text = scriptErrorStyle.Render(text)
+		}
+		scriptsTabString += text + "\n"
+	}
+	return lipgloss.Place(m.mainTabWidth, m.bodyHeight, lipgloss.Left, lipgloss.Top, responseBodyStyle.Render(scriptsTabString),
+		lipgloss.WithWhitespaceBackground(responseBodyStyle.GetBackground())) + "\n"
+}
+
+// consoleHeight is how many lines of the Scripts tab go to the console, with the rest for the script being edited.
+func consoleHeight(bodyHeight int) int {
+	return max(3, bodyHeight/3)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	lipgloss "github.com/charmbracelet/lipgloss"
+)
+
+var searchMatchStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#222222")).Background(lipgloss.Color("#ffd23f"))
+var currentSearchMatchStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#222222")).Background(lipgloss.Color("#f4a261"))
+
+// searchMatch is where a match is in the viewport's lines, with start and end being byte offsets into the line.
+type searchMatch struct {
+	line  int
+	start int
+	end   int
+}
+
+// searchPattern compiles what was typed into the search input. Plain searches ignore case, since that's almost always
+// what's wanted when looking through a response; regex searches can add (?i) themselves.
+func searchPattern(query string, useRegex bool) (*regexp.Regexp, error) {
+	if !useRegex {
+		return regexp.MustCompile("(?i)" + regexp.QuoteMeta(query)), nil
+	}
+	pattern, err := regexp.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex: %w", err)
+	}
+	return pattern, nil
+}
+
+func findSearchMatches(lines []string, pattern *regexp.Regexp) []searchMatch {
+	matches := []searchMatch{}
+	for i, line := range lines {
+		for _, loc := range pattern.FindAllStringIndex(line, -1) {
+			// patterns like a* can match nothing, which there's nothing to highlight or jump to
+			if loc[0] == loc[1] {
+				continue
+			}
+			matches = append(matches, searchMatch{line: i, start: loc[0], end: loc[1]})
+		}
+	}
+	return matches
+}
+
+// highlightSearchMatches renders line with its matches highlighted and the rest in base. matches are the matches on
+// this line in order, and current is the index into them of the match that's being looked at, or -1.
+func highlightSearchMatches(line string, matches []searchMatch, current int, base lipgloss.Style) string {
+	var s strings.Builder
+	end := 0
+	for i, match := range matches {
+		s.WriteString(base.Render(line[end:match.start]))
+		style := searchMatchStyle
+		if i == current {
+			style = currentSearchMatchStyle
+		}
+		s.WriteString(style.Render(line[match.start:match.end]))
+		end = match.end
+	}
+	s.WriteString(base.Render(line[end:]))
+	return s.String()
+}
+
+// updateSearchMatches finds the search in the lines currently in the viewport. It's called whenever those lines change,
+// so the current match is kept when it's still in range.
+func (m *model) updateSearchMatches() {
+	m.searchMatches = nil
+	m.searchErr = nil
+	if m.searchQuery == "" {
+		return
+	}
+	pattern, err := searchPattern(m.searchQuery, m.searchRegex)
+	if err != nil {
+		m.searchErr = err
+		return
+	}
+	m.searchMatches = findSearchMatches(m.viewportLines, pattern)
+	if m.currentMatch >= len(m.searchMatches) {
+		m.currentMatch = 0
+	}
+}
+
+// gotoSearchMatch makes matches[i] the current match and scrolls it to the middle of the viewport if it's off screen.
+func (m *model) gotoSearchMatch(i int) {
+	if len(m.searchMatches) == 0 {
+		return
+	}
+	m.currentMatch = (i + len(m.searchMatches)) % len(m.searchMatches)
+	m.renderViewport()
+	line := m.searchMatches[m.currentMatch].line
+	if line < m.viewport.YOffset || line >= m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(line - m.viewport.Height/2)
+	}
+}
+
+// gotoFirstSearchMatch jumps to the first match at or below the top of the viewport, which is where someone typing in
+// a search is expecting it to show up.
+func (m *model) gotoFirstSearchMatch() {
+	for i, match := range m.searchMatches {
+		if match.line >= m.viewport.YOffset {
+			m.gotoSearchMatch(i)
+			return
+		}
+	}
+	m.gotoSearchMatch(0)
+}
+
+func (m *model) clearSearch() {
+	m.searchQuery = ""
+	m.searchMatches = nil
+	m.searchErr = nil
+	m.currentMatch = 0
+	m.renderViewport()
+}
+
+// searchStatus is the match counter shown in the status line, ex. "/token 3/12".
+func (m model) searchStatus() string {
+	if m.searchQuery == "" {
+		return ""
+	}
+	mode := "/"
+	if m.searchRegex {
+		mode = "regex /"
+	}
+	if m.searchErr != nil {
+		return fmt.Sprintf("%s%s: %s", mode, m.searchQuery, m.searchErr)
+	}
+	if len(m.searchMatches) == 0 {
+		return fmt.Sprintf("%s%s: no matches", mode, m.searchQuery)
+	}
+	return fmt.Sprintf("%s%s %d/%d", mode, m.searchQuery, m.currentMatch+1, len(m.searchMatches))
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 )
 
 func hello(w http.ResponseWriter, req *http.Request) {
@@ -248,6 +249,104 @@ func return500(w http.ResponseWriter, req *http.Request) {
 	w.WriteHeader(http.StatusInternalServerError)
 }
 
+// the mock OAuth2 client and the token it's given, for trying out client credentials auth
+const mockClientID = "residentsleeper"
+const mockClientSecret = "hunter2"
+const mockAccessToken = "mock-access-token"
+
+func oauthToken(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	clientID, clientSecret, ok := req.BasicAuth()
+	if !ok {
+		clientID, clientSecret = req.FormValue("client_id"), req.FormValue("client_secret")
+	}
+	if req.Method != "POST" || req.FormValue("grant_type") != "client_credentials" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":"unsupported_grant_type"}`)
+		return
+	}
+	if clientID != mockClientID || clientSecret != mockClientSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":"invalid_client"}`)
+		return
+	}
+	tokenResponse, _ := json.Marshal(map[string]any{"access_token": mockAccessToken, "token_type": "Bearer", "expires_in": 3600})
+	fmt.Fprint(w, string(tokenResponse))
+}
+
+// protected only answers requests with the mock OAuth2 token
+func protected(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if req.Header.Get("Authorization") != "Bearer "+mockAccessToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `"missing or wrong token"`)
+		return
+	}
+	fmt.Fprint(w, `"you're in"`)
+}
+
+const mockSessionID = "mock-session"
+
+// login sets a session cookie, for trying out the cookie jar
+func login(w http.ResponseWriter, req *http.Request) {
+	http.SetCookie(w, &http.Cookie{Name: "session", Value: mockSessionID, Path: "/", HttpOnly: true, MaxAge: 3600})
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, `"logged in"`)
+}
+
+// session only answers requests with the cookie login sets
+func session(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	cookie, err := req.Cookie("session")
+	if err != nil || cookie.Value != mockSessionID {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `"not logged in"`)
+		return
+	}
+	fmt.Fprint(w, `"still logged in"`)
+}
+
+// redirect redirects n times before ending up at /hello, for trying out the redirect settings
+func redirect(w http.ResponseWriter, req *http.Request) {
+	n, err := strconv.Atoi(req.PathValue("n"))
+	if err != nil || n < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if n == 1 {
+		http.Redirect(w, req, "/hello", http.StatusFound)
+		return
+	}
+	http.Redirect(w, req, fmt.Sprintf("/redirect/%d", n-1), http.StatusMovedPermanently)
+}
+
+type uploadedFile struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+}
+
+// form echoes back the fields and files of form and multipart bodies, for trying out the body types
+func form(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := req.ParseMultipartForm(10 << 20); err != nil && err != http.ErrNotMultipart {
+		w.WriteHeader(http.StatusBadRequest)
+		errorResponse, _ := json.Marshal(err.Error())
+		fmt.Fprint(w, string(errorResponse))
+		return
+	}
+	files := map[string][]uploadedFile{}
+	if req.MultipartForm != nil {
+		for name, headers := range req.MultipartForm.File {
+			for _, header := range headers {
+				files[name] = append(files[name], uploadedFile{Filename: header.Filename, ContentType: header.Header.Get("Content-Type"), Size: header.Size})
+			}
+		}
+	}
+	echo, _ := json.Marshal(map[string]any{"contentType": req.Header.Get("Content-Type"), "fields": req.PostForm, "files": files})
+	fmt.Fprint(w, string(echo))
+}
+
 func main() {
 	http.HandleFunc("/hello", hello)
 	http.HandleFunc("/headers", headers)
@@ -256,6 +355,12 @@ func main() {
 	http.HandleFunc("/5xxtest", return500)
 	http.HandleFunc("/user/{key}", mockAPI)
 	http.HandleFunc("/users", returnMockUsers)
+	http.HandleFunc("/oauth/token", oauthToken)
+	http.HandleFunc("/protected", protected)
+	http.HandleFunc("/form", form)
+	http.HandleFunc("/login", login)
+	http.HandleFunc("/session", session)
+	http.HandleFunc("/redirect/{n}", redirect)
 
 	http.ListenAndServe(":8090", nil)
 }
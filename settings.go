@@ -0,0 +1,313 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	lipgloss "github.com/charmbracelet/lipgloss"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// workspaceSettings apply to every query in the workspace unless the query overrides them.
+type workspaceSettings struct {
+	timeout                time.Duration
+	simulatedLatency       time.Duration
+	simulatedLatencyJitter time.Duration
+	// persistCookies saves the cookie jar next to the workspace, so logins last between runs
+	persistCookies bool
+}
+
+// settingField is one editable row in the Settings tab. Values are edited as text, so set is responsible for parsing
+// and validating what was typed in. Fields that can be left empty use unset to describe what happens then.
+type settingField struct {
+	label string
+	get   func(m model) string
+	unset func(m model) string
+	set   func(m *model, value string) error
+}
+
+var settingFields = []settingField{
+	{
+		label: "timeout",
+		get:   func(m model) string { return formatQueryTimeout(m.currentQueryData.timeout) },
+		unset: func(m model) string {
+			return fmt.Sprintf("(workspace default: %s)", formatTimeout(m.settings.timeout))
+		},
+		set: func(m *model, value string) error {
+			timeout, err := parseQueryTimeout(value)
+			if err != nil {
+				return err
+			}
+			m.currentQueryData.timeout = timeout
+			return nil
+		},
+	},
+	{
+		label: "workspace default timeout",
+		get:   func(m model) string { return formatTimeout(m.settings.timeout) },
+		set: func(m *model, value string) error {
+			timeout, err := parseTimeout(value)
+			if err != nil {
+				return err
+			}
+			m.settings.timeout = timeout
+			return nil
+		},
+	},
+	{
+		label: "simulated latency",
+		get:   func(m model) string { return formatOptionalDuration(m.currentQueryData.simulatedLatency) },
+		unset: func(m model) string {
+			return fmt.Sprintf("(workspace default: %s)", formatLatency(m.settings.simulatedLatency, m.settings.simulatedLatencyJitter))
+		},
+		set: func(m *model, value string) error {
+			latency, err := parseOptionalDuration(value)
+			if err != nil {
+				return err
+			}
+			m.currentQueryData.simulatedLatency = latency
+			return nil
+		},
+	},
+	{
+		label: "simulated latency jitter",
+		get:   func(m model) string { return formatOptionalDuration(m.currentQueryData.simulatedLatencyJitter) },
+		unset: func(m model) string { return "none" },
+		set: func(m *model, value string) error {
+			jitter, err := parseOptionalDuration(value)
+			if err != nil {
+				return err
+			}
+			m.currentQueryData.simulatedLatencyJitter = jitter
+			return nil
+		},
+	},
+	{
+		label: "workspace default simulated latency",
+		get:   func(m model) string { return formatOptionalDuration(m.settings.simulatedLatency) },
+		unset: func(m model) string { return "none" },
+		set: func(m *model, value string) error {
+			latency, err := parseOptionalDuration(value)
+			if err != nil {
+				return err
+			}
+			m.settings.simulatedLatency = latency
+			return nil
+		},
+	},
+	{
+		label: "workspace default simulated latency jitter",
+		get:   func(m model) string { return formatOptionalDuration(m.settings.simulatedLatencyJitter) },
+		unset: func(m model) string { return "none" },
+		set: func(m *model, value string) error {
+			jitter, err := parseOptionalDuration(value)
+			if err != nil {
+				return err
+			}
+			m.settings.simulatedLatencyJitter = jitter
+			return nil
+		},
+	},
+	{
+		label: "redirects",
+		get:   func(m model) string { return formatMaxRedirects(m.currentQueryData.maxRedirects) },
+		unset: func(m model) string {
+			return fmt.Sprintf("follow up to %d (off shows the redirect itself)", defaultMaxRedirects)
+		},
+		set: func(m *model, value string) error {
+			maxRedirects, err := parseMaxRedirects(value)
+			if err != nil {
+				return err
+			}
+			m.currentQueryData.maxRedirects = maxRedirects
+			return nil
+		},
+	},
+	{
+		label: "cookie jar",
+		get: func(m model) string {
+			if m.currentQueryData.skipCookieJar {
+				return "skip"
+			}
+			return ""
+		},
+		unset: func(m model) string { return "use (skip sends only the query's own cookies)" },
+		set: func(m *model, value string) error {
+			skip, err := parseToggle(value, "skip", "use")
+			if err != nil {
+				return err
+			}
+			m.currentQueryData.skipCookieJar = skip
+			return nil
+		},
+	},
+	{
+		label: "workspace saves cookies",
+		get: func(m model) string {
+			if m.settings.persistCookies {
+				return "on"
+			}
+			return "off"
+		},
+		set: func(m *model, value string) error {
+			persist, err := parseToggle(value, "on", "off")
+			if err != nil {
+				return err
+			}
+			m.settings.persistCookies = persist
+			if !persist && m.workspacePath != "" {
+				// turning it off shouldn't leave the cookies sitting on disk
+				if err := os.Remove(cookiesPath(m.workspacePath)); err != nil && !errors.Is(err, os.ErrNotExist) {
+					return err
+				}
+			}
+			return m.saveCookieJar()
+		},
+	},
+}
+
+// requestTimeout is how long the current query gets before it's given up on; 0 means it can take forever.
+func (m model) requestTimeout() time.Duration {
+	return queryTimeout(*m.currentQueryData, m.settings)
+}
+
+func queryTimeout(query QueryData, settings workspaceSettings) time.Duration {
+	switch {
+	case query.timeout == noTimeout:
+		return 0
+	case query.timeout != 0:
+		return query.timeout
+	}
+	return settings.timeout
+}
+
+// queryRequestOptions collects the settings for sending query with jar. Simulated latency is off unless the query or
+// the workspace turns it on, so response times are real by default.
+func queryRequestOptions(query QueryData, settings workspaceSettings, jar *cookieJar) requestOptions {
+	options := requestOptions{
+		simulatedLatency:       settings.simulatedLatency,
+		simulatedLatencyJitter: settings.simulatedLatencyJitter,
+	}
+	if !query.skipCookieJar {
+		options.jar = jar
+	}
+	switch {
+	case query.maxRedirects < 0:
+		options.maxRedirects = 0
+	case query.maxRedirects == 0:
+		options.maxRedirects = defaultMaxRedirects
+	default:
+		options.maxRedirects = query.maxRedirects
+	}
+	if query.simulatedLatency != 0 {
+		options.simulatedLatency = query.simulatedLatency
+		options.simulatedLatencyJitter = query.simulatedLatencyJitter
+	}
+	return options
+}
+
+// parseToggle parses a setting that's one of two words, with an empty string meaning off.
+func parseToggle(s, on, off string) (bool, error) {
+	switch s {
+	case on:
+		return true, nil
+	case off, "":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q should be %s or %s", s, on, off)
+}
+
+// parseOptionalDuration parses durations like 1500ms or 5s, with an empty string meaning 0.
+func parseOptionalDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%q isn't a duration (ex. 500ms, 5s, 1m)", s)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%q can't be negative", s)
+	}
+	return d, nil
+}
+
+func formatOptionalDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// parseTimeout is parseOptionalDuration, but also accepts "none" since that's how no timeout is shown.
+func parseTimeout(s string) (time.Duration, error) {
+	if s == "none" {
+		return 0, nil
+	}
+	return parseOptionalDuration(s)
+}
+
+func formatTimeout(d time.Duration) string {
+	if d == 0 {
+		return "none"
+	}
+	return d.String()
+}
+
+// noTimeout is a query's timeout when it's turned off. A query's timeout of 0 means it uses the workspace's, so no
+// timeout is saved as -1 instead, like redirects that aren't followed.
+const noTimeout time.Duration = -1
+
+// parseQueryTimeout parses a query's timeout setting: empty for the workspace's, none for no timeout, or a duration.
+func parseQueryTimeout(s string) (time.Duration, error) {
+	if s == "none" {
+		return noTimeout, nil
+	}
+	return parseOptionalDuration(s)
+}
+
+func formatQueryTimeout(d time.Duration) string {
+	if d == noTimeout {
+		return "none"
+	}
+	return formatOptionalDuration(d)
+}
+
+func formatLatency(latency, jitter time.Duration) string {
+	if latency == 0 {
+		return "none"
+	}
+	if jitter == 0 {
+		return latency.String()
+	}
+	return fmt.Sprintf("%s ± %s", latency, jitter)
+}
+
+func buildSettingsTabString(m model) string {
+	settingsTabString := ""
+	for i, field := range settingFields {
+		value := field.get(m)
+		if value == "" && field.unset != nil {
+			value = field.unset(m)
+		}
+		fieldString := fmt.Sprintf(" %s: %s", field.label, value)
+		if i == m.focusedSetting {
+			if m.uiState == UIStateEditingSetting {
+				settingsTabString += fmt.Sprintf(" %s: ", field.label) + m.textInput.View() + "\n"
+			} else {
+				focusedStyle := tabOpenStyle
+				if m.uiState == UIStateSelectingQuery {
+					focusedStyle = responseBodyStyle
+				}
+				settingsTabString += focusedStyle.Render(fieldString) + "\n"
+			}
+		} else {
+			settingsTabString += fieldString + "\n"
+		}
+	}
+	return lipgloss.Place(m.mainTabWidth, m.bodyHeight, lipgloss.Left, lipgloss.Top, responseBodyStyle.Render(settingsTabString),
+		lipgloss.WithWhitespaceBackground(responseBodyStyle.GetBackground())) + "\n"
+}
@@ -0,0 +1,487 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// workspaceFile is the on-disk format for a workspace. The structs the UI works with keep their fields unexported,
+// so these mirror them with json tags instead of adding tags to the UI structs.
+type workspaceFile struct {
+	Queries           []savedQuery       `json:"queries"`
+	Environments      []savedEnvironment `json:"environments,omitempty"`
+	ActiveEnvironment string             `json:"activeEnvironment,omitempty"`
+	Settings          savedSettings      `json:"settings"`
+}
+
+type savedSettings struct {
+	// a pointer so a missing timeout can be told apart from no timeout
+	Timeout                *duration `json:"timeout,omitempty"`
+	SimulatedLatency       duration  `json:"simulatedLatency,omitempty"`
+	SimulatedLatencyJitter duration  `json:"simulatedLatencyJitter,omitempty"`
+	PersistCookies         bool      `json:"persistCookies,omitempty"`
+}
+
+type savedEnvironment struct {
+	Name      string            `json:"name"`
+	Variables map[string]string `json:"variables"`
+}
+
+type savedQuery struct {
+	Name        string      `json:"name"`
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	Headers     []savedPair `json:"headers,omitempty"`
+	QueryParams []savedPair `json:"queryParams,omitempty"`
+	Body        string      `json:"body,omitempty"`
+	BodyMode    string      `json:"bodyMode,omitempty"`
+	FormFields  []savedPair `json:"formFields,omitempty"`
+	Assertions  []string    `json:"assertions,omitempty"`
+	Extractions []string    `json:"extractions,omitempty"`
+	PreScript   string      `json:"preScript,omitempty"`
+	PostScript  string      `json:"postScript,omitempty"`
+	Auth        *savedAuth  `json:"auth,omitempty"`
+	Timeout     duration    `json:"timeout,omitempty"`
+	// simulated latency is only for demos, so it's opt in per query or for the whole workspace
+	SimulatedLatency       duration `json:"simulatedLatency,omitempty"`
+	SimulatedLatencyJitter duration `json:"simulatedLatencyJitter,omitempty"`
+	MaxRedirects           int      `json:"maxRedirects,omitempty"`
+	SkipCookieJar          bool     `json:"skipCookieJar,omitempty"`
+}
+
+// savedAuth is a query's auth as it's written to the workspace. Secrets are saved as typed in, so they're best kept
+// in variables (ex. {{apiToken}}) when the workspace is shared.
+type savedAuth struct {
+	Mode         string `json:"mode"`
+	Username     string `json:"username,omitempty"`
+	Password     string `json:"password,omitempty"`
+	Token        string `json:"token,omitempty"`
+	KeyName      string `json:"keyName,omitempty"`
+	KeyValue     string `json:"keyValue,omitempty"`
+	KeyInQuery   bool   `json:"keyInQuery,omitempty"`
+	TokenURL     string `json:"tokenUrl,omitempty"`
+	ClientID     string `json:"clientId,omitempty"`
+	ClientSecret string `json:"clientSecret,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+type savedPair struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// duration is a time.Duration that's written as a string like "5s" in workspace files. A query's timeout that's turned
+// off (noTimeout) is written as none.
+type duration time.Duration
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	if time.Duration(d) == noTimeout {
+		return json.Marshal("none")
+	}
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := parseQueryTimeout(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+// loadWorkspace reads the workspace file at path. If the file doesn't exist yet, the demo queries for the mock server
+// are used instead so there's something to play with; they get written to path the first time the workspace is saved.
+func loadWorkspace(path string) (workspaceFile, error) {
+	ws, err := readWorkspaceFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return workspaceFile{
+			Queries:           savedQueriesFromQueryData(defaultQueries()),
+			Environments:      savedEnvironmentsFromEnvironments(defaultEnvironments()),
+			ActiveEnvironment: defaultEnvironments()[0].name,
+		}, nil
+	}
+	return ws, err
+}
+
+func readWorkspaceFile(path string) (workspaceFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return workspaceFile{}, err
+	}
+
+	var ws workspaceFile
+	if err := json.Unmarshal(data, &ws); err != nil {
+		return workspaceFile{}, fmt.Errorf("parsing workspace %s: %w", path, err)
+	}
+	return ws, nil
+}
+
+// saveWorkspace writes to a temp file first and renames it over the old one, so quitting mid-write can't leave a
+// half-written workspace behind.
+func saveWorkspace(path string, ws workspaceFile) error {
+	data, err := json.MarshalIndent(ws, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".workspace-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (m model) workspaceFile() workspaceFile {
+	timeout := duration(m.settings.timeout)
+	ws := workspaceFile{
+		Queries:      savedQueriesFromQueryData(m.queries),
+		Environments: savedEnvironmentsFromEnvironments(m.environments),
+		Settings: savedSettings{
+			Timeout:                &timeout,
+			SimulatedLatency:       duration(m.settings.simulatedLatency),
+			SimulatedLatencyJitter: duration(m.settings.simulatedLatencyJitter),
+			PersistCookies:         m.settings.persistCookies,
+		},
+	}
+	if m.currentEnvironment >= 0 && m.currentEnvironment < len(m.environments) {
+		ws.ActiveEnvironment = m.environments[m.currentEnvironment].name
+	}
+	return ws
+}
+
+// persistWorkspace saves the workspace after a change; failures are shown in the status bar rather than interrupting
+// whatever the user is doing.
+func (m *model) persistWorkspace() {
+	if m.workspacePath == "" {
+		return
+	}
+	if err := saveWorkspace(m.workspacePath, m.workspaceFile()); err != nil {
+		m.statusMessage = fmt.Sprintf("couldn't save workspace: %s", err)
+	}
+}
+
+func (ws workspaceFile) queryData() []QueryData {
+	queries := []QueryData{}
+	for _, saved := range ws.Queries {
+		query := QueryData{
+			name:                   saved.Name,
+			url:                    saved.URL,
+			body:                   []byte(saved.Body),
+			bodyMode:               bodyMode(saved.BodyMode),
+			headers:                []HeaderData{},
+			queryParams:            []QueryParamData{},
+			requestMethod:          HTTPMethod(saved.Method),
+			assertions:             saved.Assertions,
+			extractions:            saved.Extractions,
+			preScript:              saved.PreScript,
+			postScript:             saved.PostScript,
+			timeout:                time.Duration(saved.Timeout),
+			simulatedLatency:       time.Duration(saved.SimulatedLatency),
+			simulatedLatencyJitter: time.Duration(saved.SimulatedLatencyJitter),
+			maxRedirects:           saved.MaxRedirects,
+			skipCookieJar:          saved.SkipCookieJar,
+		}
+		if query.requestMethod == "" {
+			query.requestMethod = GET
+		}
+		if saved.Auth != nil {
+			query.auth = queryAuth{
+				mode:         authMode(saved.Auth.Mode),
+				username:     saved.Auth.Username,
+				password:     saved.Auth.Password,
+				token:        saved.Auth.Token,
+				keyName:      saved.Auth.KeyName,
+				keyValue:     saved.Auth.KeyValue,
+				keyInQuery:   saved.Auth.KeyInQuery,
+				tokenURL:     saved.Auth.TokenURL,
+				clientID:     saved.Auth.ClientID,
+				clientSecret: saved.Auth.ClientSecret,
+				scope:        saved.Auth.Scope,
+			}
+		}
+		for _, header := range saved.Headers {
+			query.headers = append(query.headers, HeaderData{name: header.Name, value: header.Value})
+		}
+		for _, param := range saved.QueryParams {
+			query.queryParams = append(query.queryParams, QueryParamData{name: param.Name, value: param.Value})
+		}
+		for _, field := range saved.FormFields {
+			query.formFields = append(query.formFields, QueryParamData{name: field.Name, value: field.Value})
+		}
+		queries = append(queries, query)
+	}
+	return queries
+}
+
+func (ws workspaceFile) settings() workspaceSettings {
+	settings := workspaceSettings{
+		timeout:                defaultTimeout,
+		simulatedLatency:       time.Duration(ws.Settings.SimulatedLatency),
+		simulatedLatencyJitter: time.Duration(ws.Settings.SimulatedLatencyJitter),
+		persistCookies:         ws.Settings.PersistCookies,
+	}
+	if ws.Settings.Timeout != nil {
+		settings.timeout = time.Duration(*ws.Settings.Timeout)
+	}
+	return settings
+}
+
+func (ws workspaceFile) environments() []Environment {
+	environments := []Environment{}
+	for _, saved := range ws.Environments {
+		variables := map[string]string{}
+		for name, value := range saved.Variables {
+			variables[name] = value
+		}
+		environments = append(environments, Environment{name: saved.Name, variables: variables})
+	}
+	return environments
+}
+
+func savedEnvironmentsFromEnvironments(environments []Environment) []savedEnvironment {
+	savedEnvironments := []savedEnvironment{}
+	for _, env := range environments {
+		savedEnvironments = append(savedEnvironments, savedEnvironment{Name: env.name, Variables: env.variables})
+	}
+	return savedEnvironments
+}
+
+func savedQueriesFromQueryData(queries []QueryData) []savedQuery {
+	savedQueries := []savedQuery{}
+	for _, query := range queries {
+		saved := savedQuery{
+			Name:                   query.name,
+			Method:                 string(query.requestMethod),
+			URL:                    query.url,
+			Body:                   string(query.body),
+			BodyMode:               string(query.bodyMode),
+			Assertions:             query.assertions,
+			Extractions:            query.extractions,
+			PreScript:              query.preScript,
+			PostScript:             query.postScript,
+			Timeout:                duration(query.timeout),
+			SimulatedLatency:       duration(query.simulatedLatency),
+			SimulatedLatencyJitter: duration(query.simulatedLatencyJitter),
+			MaxRedirects:           query.maxRedirects,
+			SkipCookieJar:          query.skipCookieJar,
+		}
+		if auth := query.auth; auth != (queryAuth{}) && auth != (queryAuth{mode: authNone}) {
+			saved.Auth = &savedAuth{
+				Mode:         string(auth.mode),
+				Username:     auth.username,
+				Password:     auth.password,
+				Token:        auth.token,
+				KeyName:      auth.keyName,
+				KeyValue:     auth.keyValue,
+				KeyInQuery:   auth.keyInQuery,
+				TokenURL:     auth.tokenURL,
+				ClientID:     auth.clientID,
+				ClientSecret: auth.clientSecret,
+				Scope:        auth.scope,
+			}
+		}
+		for _, header := range query.headers {
+			saved.Headers = append(saved.Headers, savedPair{Name: header.name, Value: header.value})
+		}
+		for _, param := range query.queryParams {
+			saved.QueryParams = append(saved.QueryParams, savedPair{Name: param.name, Value: param.value})
+		}
+		for _, field := range query.formFields {
+			saved.FormFields = append(saved.FormFields, savedPair{Name: field.name, Value: field.value})
+		}
+		savedQueries = append(savedQueries, saved)
+	}
+	return savedQueries
+}
+
+// defaultEnvironments point the default queries at the mock server.
+func defaultEnvironments() []Environment {
+	return []Environment{
+		{name: "local", variables: map[string]string{"baseUrl": "http://localhost:8090"}},
+	}
+}
+
+// defaultQueries are a few queries for the bundled mock server, used when there's no workspace file yet.
+func defaultQueries() []QueryData {
+	return []QueryData{
+		{
+			name: "mock server hello",
+			url:  "{{baseUrl}}/hello",
+			body: []byte(" "),
+			headers: []HeaderData{
+				{name: "Accept", value: "application/json;v=2"},
+				{name: "Content-Type", value: "application/json"},
+				{name: "User-Agent", value: "dylanpruitt-go-client"},
+			},
+			queryParams:   []QueryParamData{},
+			requestMethod: GET,
+			responseData:  nil,
+		},
+		{
+			name: "mock server headers",
+			url:  "{{baseUrl}}/headers",
+			body: []byte(" "),
+			headers: []HeaderData{
+				{name: "Accept", value: "*/*"},
+				{name: "Content-Type", value: "application/json"},
+				{name: "User-Agent", value: "dylanpruitt-go-client"},
+			},
+			queryParams:   []QueryParamData{},
+			requestMethod: GET,
+			responseData:  nil,
+		},
+		{
+			name: "mock api call v1",
+			url:  "{{baseUrl}}/user/1",
+			body: []byte(" "),
+			headers: []HeaderData{
+				{name: "Accept", value: "application/json;v=1"},
+				{name: "Content-Type", value: "application/json"},
+				{name: "User-Agent", value: "dylanpruitt-go-client"},
+			},
+			queryParams:   []QueryParamData{},
+			requestMethod: GET,
+			responseData:  nil,
+		},
+		{
+			name: "mock api call v2",
+			url:  "{{baseUrl}}/user/1",
+			body: []byte(" "),
+			headers: []HeaderData{
+				{name: "Accept", value: "application/json;v=2"},
+				{name: "Content-Type", value: "application/json"},
+				{name: "User-Agent", value: "dylanpruitt-go-client"},
+			},
+			queryParams:   []QueryParamData{},
+			requestMethod: GET,
+			responseData:  nil,
+		},
+		{
+			name: "mock api call 404",
+			url:  "{{baseUrl}}/user/doesntexistlmao",
+			body: []byte(" "),
+			headers: []HeaderData{
+				{name: "Accept", value: "application/json;v=2"},
+				{name: "Content-Type", value: "application/json"},
+				{name: "User-Agent", value: "dylanpruitt-go-client"},
+			},
+			queryParams:   []QueryParamData{},
+			requestMethod: GET,
+			responseData:  nil,
+		},
+		{
+			name: "mock api post",
+			url:  "{{baseUrl}}/user/2",
+			body: []byte("{\"FirstName\":\"Ben\",\"LastName\":\"L\",\"Money\":0}"),
+			headers: []HeaderData{
+				{name: "Content-Type", value: "application/json"},
+				{name: "User-Agent", value: "dylanpruitt-go-client"},
+			},
+			queryParams:   []QueryParamData{},
+			requestMethod: POST,
+			responseData:  nil,
+		},
+		{
+			name: "mock api get all users",
+			url:  "{{baseUrl}}/users",
+			body: []byte(" "),
+			headers: []HeaderData{
+				{name: "Accept", value: "*/*"},
+				{name: "Content-Type", value: "application/json"},
+				{name: "User-Agent", value: "dylanpruitt-go-client"},
+			},
+			queryParams:   []QueryParamData{},
+			requestMethod: GET,
+			responseData:  nil,
+		},
+		{
+			name: "mock server oauth2",
+			url:  "{{baseUrl}}/protected",
+			body: []byte(" "),
+			headers: []HeaderData{
+				{name: "Accept", value: "*/*"},
+				{name: "User-Agent", value: "dylanpruitt-go-client"},
+			},
+			queryParams:   []QueryParamData{},
+			requestMethod: GET,
+			responseData:  nil,
+			auth: queryAuth{
+				mode:         authOAuth2,
+				tokenURL:     "{{baseUrl}}/oauth/token",
+				clientID:     "residentsleeper",
+				clientSecret: "hunter2",
+			},
+		},
+		{
+			name: "mock server login",
+			url:  "{{baseUrl}}/login",
+			body: []byte(" "),
+			headers: []HeaderData{
+				{name: "Accept", value: "*/*"},
+				{name: "User-Agent", value: "dylanpruitt-go-client"},
+			},
+			queryParams:   []QueryParamData{},
+			requestMethod: POST,
+			responseData:  nil,
+		},
+		{
+			name: "mock server session",
+			url:  "{{baseUrl}}/session",
+			body: []byte(" "),
+			headers: []HeaderData{
+				{name: "Accept", value: "*/*"},
+				{name: "User-Agent", value: "dylanpruitt-go-client"},
+			},
+			queryParams:   []QueryParamData{},
+			requestMethod: GET,
+			responseData:  nil,
+		},
+		{
+			name:     "mock server form",
+			url:      "{{baseUrl}}/form",
+			body:     []byte(" "),
+			bodyMode: bodyForm,
+			formFields: []QueryParamData{
+				{name: "firstName", value: "John"},
+				{name: "lastName", value: "Wick"},
+			},
+			headers: []HeaderData{
+				{name: "Accept", value: "*/*"},
+				{name: "User-Agent", value: "dylanpruitt-go-client"},
+			},
+			queryParams:   []QueryParamData{},
+			requestMethod: POST,
+			responseData:  nil,
+		},
+		{
+			name: "long response",
+			url:  "{{baseUrl}}/long-response",
+			body: []byte(" "),
+			headers: []HeaderData{
+				{name: "Accept", value: "*/*"},
+				{name: "User-Agent", value: "dylanpruitt-go-client"},
+			},
+			queryParams:   []QueryParamData{},
+			requestMethod: GET,
+			responseData:  nil,
+		},
+	}
+}